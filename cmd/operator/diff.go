@@ -0,0 +1,199 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/diff"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	cmo "github.com/openshift/cluster-monitoring-operator/pkg/operator"
+	"github.com/openshift/cluster-monitoring-operator/pkg/render"
+)
+
+// Diff implements the diff subcommand, which compares the objects the
+// operator would reconcile for a configuration to the live objects.
+func Diff(args []string) int {
+	flagset := flag.NewFlagSet("diff", flag.ExitOnError)
+	namespace := flagset.String("namespace", "openshift-monitoring", "Namespace the cluster monitoring stack is deployed in.")
+	configMapName := flagset.String("configmap", "cluster-monitoring-config", "ConfigMap holding the configuration of the cluster monitoring stack, used if --config is not specified.")
+	configFile := flagset.String("config", "", "Path to the config.yaml to compare. The configuration of the cluster is used if not specified.")
+	kubeconfig := flagset.String("kubeconfig", "", "Path to a kubeconfig file. Only required when running out of cluster.")
+	master := flagset.String("master", "", "Address of the Kubernetes API server, overriding the one of the kubeconfig.")
+	kubeContext := flagset.String("context", "", "Name of the kubeconfig context to use. Defaults to the current context of the kubeconfig.")
+	tags := tags{}
+	flagset.Var(&tags, "tags", "Tags to use for images.")
+	flagset.Parse(args)
+
+	cfg, err := buildConfig(*kubeconfig, *master, *kubeContext)
+	if err != nil {
+		fmt.Fprint(os.Stderr, "loading Kubernetes client configuration failed: ", err)
+		return 1
+	}
+
+	c, err := client.New(cfg, *namespace, *configMapName)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+
+	var config *manifests.Config
+	if *configFile != "" {
+		config, err = loadConfig(*configFile)
+	} else {
+		config, err = clusterConfig(c, *namespace, *configMapName)
+	}
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+	config.SetTagOverrides(tags.asMap())
+
+	platform, err := cmo.DetectPlatform(c)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+	config.SetPlatform(platform)
+
+	components, err := render.Render(*namespace, config, liveHost(c), liveSecret(c, *namespace))
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+
+	results, err := diff.Diff(components, c)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+
+	printResults(os.Stdout, results)
+	return 0
+}
+
+// clusterConfig returns the configuration of the cluster, like the operator
+// reads it.
+func clusterConfig(c *client.Client, namespace, name string) (*manifests.Config, error) {
+	cm, err := c.KubernetesInterface().CoreV1().ConfigMaps(namespace).Get(name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return manifests.NewDefaultConfig(), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving the Cluster Monitoring ConfigMap failed")
+	}
+
+	content, ok := cm.Data["config.yaml"]
+	if !ok {
+		return manifests.NewDefaultConfig(), nil
+	}
+
+	config, err := manifests.NewConfigFromString(content)
+	return config, errors.Wrap(err, "the Cluster Monitoring config is invalid")
+}
+
+// liveHost returns the host of the live Route or load balancer exposing a
+// component, so that the external URLs are rendered like the operator does.
+func liveHost(c *client.Client) render.HostFunc {
+	return func(obj runtime.Object) (string, error) {
+		switch o := obj.(type) {
+		case *routev1.Route:
+			live := o.DeepCopy()
+			err := c.Get(context.Background(), live)
+			if apierrors.IsNotFound(err) {
+				return o.Spec.Host, nil
+			}
+			return live.Spec.Host, err
+		case *v1.Service:
+			if o.Spec.Type != v1.ServiceTypeLoadBalancer || len(o.Spec.Ports) == 0 {
+				return "", nil
+			}
+			live := o.DeepCopy()
+			err := c.Get(context.Background(), live)
+			if apierrors.IsNotFound(err) {
+				return "", nil
+			}
+			if err != nil {
+				return "", err
+			}
+			if len(live.Status.LoadBalancer.Ingress) == 0 {
+				return "", nil
+			}
+			host := live.Status.LoadBalancer.Ingress[0].Hostname
+			if ip := live.Status.LoadBalancer.Ingress[0].IP; ip != "" {
+				host = ip
+			}
+			return net.JoinHostPort(host, strconv.Itoa(int(o.Spec.Ports[0].Port))), nil
+		}
+		return "", nil
+	}
+}
+
+// liveSecret returns the live Secrets of the namespace, so that the hash of
+// the remote write Secrets is rendered like the operator does.
+func liveSecret(c *client.Client, namespace string) render.SecretFunc {
+	return func(name string) (*v1.Secret, error) {
+		s := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name}}
+		return s, c.Get(context.Background(), s)
+	}
+}
+
+func printResults(w io.Writer, results []*diff.Result) {
+	counts := map[diff.Action]int{}
+	for _, r := range results {
+		counts[r.Action]++
+
+		name := r.Name
+		if r.Namespace != "" {
+			name = r.Namespace + "/" + name
+		}
+
+		switch r.Action {
+		case diff.ActionCreate:
+			fmt.Fprintf(w, "+ %s %s would be created\n", r.Kind, name)
+		case diff.ActionOrphan:
+			fmt.Fprintf(w, "- %s %s would be left orphaned\n", r.Kind, name)
+		case diff.ActionUpdate:
+			fmt.Fprintf(w, "~ %s %s would be updated\n", r.Kind, name)
+			for _, ch := range r.Changes {
+				fmt.Fprintf(w, "    %s: %s -> %s\n", ch.Path, orNone(ch.Live), orNone(ch.Desired))
+			}
+		}
+	}
+
+	fmt.Fprintf(w, "%d to create, %d to update, %d unchanged, %d orphaned\n",
+		counts[diff.ActionCreate], counts[diff.ActionUpdate], counts[diff.ActionNone], counts[diff.ActionOrphan])
+}
+
+func orNone(v string) string {
+	if v == "" {
+		return "<none>"
+	}
+	return v
+}
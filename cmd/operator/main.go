@@ -18,14 +18,23 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/sync/errgroup"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/openshift/cluster-monitoring-operator/pkg/leaderelection"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	cmo "github.com/openshift/cluster-monitoring-operator/pkg/operator"
 )
 
@@ -43,7 +52,7 @@ func (t *tags) Set(value string) error {
 	for _, pair := range pairs {
 		splitPair := strings.Split(pair, "=")
 		if len(splitPair) != 2 {
-			return fmt.Errorf("Pair %v is malformed. Key value pairs must be in the form of \"key=value\". Multiple pairs must be comma separated.")
+			return fmt.Errorf("Pair %v is malformed. Key value pairs must be in the form of \"key=value\". Multiple pairs must be comma separated.", pair)
 		}
 		imageName := splitPair[0]
 		imageTag := splitPair[1]
@@ -73,9 +82,38 @@ func (t *tags) Type() string {
 }
 
 func Main() int {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "render":
+			return Render(os.Args[2:])
+		case "diff":
+			return Diff(os.Args[2:])
+		}
+	}
+
 	flagset := flag.CommandLine
 	namespace := flagset.String("namespace", "openshift-monitoring", "Namespace to deploy and manage cluster monitoring stack in.")
 	configMapName := flagset.String("configmap", "cluster-monitoring-config", "ConfigMap name to configure the cluster monitoring stack.")
+	listenAddress := flagset.String("listen-address", ":8080", "Address on which to expose the operator's own metrics.")
+	leaderElect := flagset.Bool("leader-elect", true, "Elect a leader among the operator replicas, only the leader reconciles the cluster monitoring stack.")
+	leaderElectLock := flagset.String("leader-elect-lock", "cluster-monitoring-operator-lock", "Name of the ConfigMap used as the leader election lock.")
+	leaderElectIdentity := flagset.String("leader-elect-identity", "", "Identity of this replica in leader election. Defaults to the hostname.")
+	leaderElectLeaseDuration := flagset.Duration("leader-elect-lease-duration", 15*time.Second, "Duration non-leader replicas wait before trying to acquire a lease that was not renewed.")
+	leaderElectRenewDeadline := flagset.Duration("leader-elect-renew-deadline", 10*time.Second, "Duration the leader retries renewing its lease before giving up leadership.")
+	leaderElectRetryPeriod := flagset.Duration("leader-elect-retry-period", 2*time.Second, "Duration between attempts to acquire or renew the lease.")
+	kubeconfig := flagset.String("kubeconfig", "", "Path to a kubeconfig file. Only required when running out of cluster.")
+	master := flagset.String("master", "", "Address of the Kubernetes API server, overriding the one of the kubeconfig. Only required when running out of cluster.")
+	kubeContext := flagset.String("context", "", "Name of the kubeconfig context to use. Defaults to the current context of the kubeconfig.")
+	kubeAPIQPS := flagset.Float64("kube-api-qps", float64(rest.DefaultQPS), "Maximum queries per second to the Kubernetes API server.")
+	kubeAPIBurst := flagset.Int("kube-api-burst", rest.DefaultBurst, "Maximum burst of queries to the Kubernetes API server.")
+	userAgent := flagset.String("user-agent", "cluster-monitoring-operator", "User agent sent to the Kubernetes API server.")
+	rolloutTimeout := flagset.Duration("rollout-timeout", 0, "Maximum duration to wait for the objects of a component to be ready. Defaults to the timeout of each wait, 5m for rollouts. Overridden by the retryPolicy of the component.")
+	pollInterval := flagset.Duration("poll-interval", 0, "Interval at which the readiness of objects is checked again when no change is observed. Defaults to the interval of each wait. Overridden by the retryPolicy of the component.")
+	taskMaxRetries := flagset.Int("task-max-retries", 0, "Number of times a failed task is retried before the reconciliation fails. Overridden by the retryPolicy of the component.")
+	taskRetryBackoff := flagset.Duration("task-retry-backoff", 5*time.Second, "Delay before retrying a failed task, doubled on every retry. Overridden by the retryPolicy of the component.")
+	taskMaxRetryBackoff := flagset.Duration("task-max-retry-backoff", time.Minute, "Maximum delay between retries of a failed task. Overridden by the retryPolicy of the component.")
+	prune := flagset.String("prune", string(cmo.PruneDryRun), "Whether managed objects that are not desired anymore are deleted after a successful reconciliation: enabled, dry-run (only log them) or disabled.")
+	syncMaxBackoff := flagset.Duration("sync-max-backoff", 1000*time.Second, "Maximum delay between retries of a failed reconciliation.")
 	tags := tags{}
 	flag.Var(&tags, "tags", "Tags to use for images.")
 	flag.Parse()
@@ -88,18 +126,84 @@ func Main() int {
 		fmt.Fprint(os.Stderr, "`--configmap` flag is required, but not specified.")
 	}
 
-	o, err := cmo.New(*namespace, *configMapName, tags.asMap())
+	pruneMode := cmo.PruneMode(*prune)
+	switch pruneMode {
+	case cmo.PruneEnabled, cmo.PruneDryRun, cmo.PruneDisabled:
+	default:
+		fmt.Fprintf(os.Stderr, "invalid value %q for `--prune`, must be one of enabled, dry-run or disabled", *prune)
+		return 1
+	}
+
+	var lec *leaderelection.Config
+	if *leaderElect {
+		identity := *leaderElectIdentity
+		if identity == "" {
+			hostname, err := os.Hostname()
+			if err != nil {
+				fmt.Fprint(os.Stderr, "determining leader election identity failed: ", err)
+				return 1
+			}
+			identity = hostname
+		}
+		lec = &leaderelection.Config{
+			Namespace:     *namespace,
+			Name:          *leaderElectLock,
+			Identity:      identity,
+			LeaseDuration: *leaderElectLeaseDuration,
+			RenewDeadline: *leaderElectRenewDeadline,
+			RetryPeriod:   *leaderElectRetryPeriod,
+		}
+	}
+
+	config, err := buildConfig(*kubeconfig, *master, *kubeContext)
+	if err != nil {
+		fmt.Fprint(os.Stderr, "loading Kubernetes client configuration failed: ", err)
+		return 1
+	}
+	config.QPS = float32(*kubeAPIQPS)
+	config.Burst = *kubeAPIBurst
+	config.UserAgent = *userAgent
+
+	retryPolicy := manifests.RetryPolicy{
+		MaxRetries:      taskMaxRetries,
+		RetryBackoff:    &metav1.Duration{Duration: *taskRetryBackoff},
+		MaxRetryBackoff: &metav1.Duration{Duration: *taskMaxRetryBackoff},
+	}
+	if *rolloutTimeout > 0 {
+		retryPolicy.RolloutTimeout = &metav1.Duration{Duration: *rolloutTimeout}
+	}
+	if *pollInterval > 0 {
+		retryPolicy.PollInterval = &metav1.Duration{Duration: *pollInterval}
+	}
+
+	o, err := cmo.New(config, *namespace, *configMapName, tags.asMap(), lec, retryPolicy, *syncMaxBackoff, pruneMode)
 	if err != nil {
 		fmt.Fprint(os.Stderr, err)
 		return 1
 	}
 
+	l, err := net.Listen("tcp", *listenAddress)
+	if err != nil {
+		fmt.Fprint(os.Stderr, "listening on ", *listenAddress, " failed: ", err)
+		return 1
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Handler: mux}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	wg, ctx := errgroup.WithContext(ctx)
 
-	wg.Go(func() error { return o.Run(ctx.Done()) })
+	wg.Go(func() error { return o.Run(ctx) })
+	wg.Go(func() error {
+		if err := srv.Serve(l); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	})
 
-	term := make(chan os.Signal)
+	term := make(chan os.Signal, 1)
 	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
 
 	select {
@@ -109,14 +213,31 @@ func Main() int {
 	}
 
 	cancel()
+	srv.Close()
 	if err := wg.Wait(); err != nil {
-		glog.V(4).Info("Unhandled error received. Exiting...err: %s", err)
+		glog.V(4).Infof("Unhandled error received. Exiting...err: %s", err)
 		return 1
 	}
 
 	return 0
 }
 
+// buildConfig returns the in-cluster configuration, unless a kubeconfig, an
+// API server address or a context is given, in which case the configuration
+// is loaded from the kubeconfig like kubectl does.
+func buildConfig(kubeconfig, master, kubeContext string) (*rest.Config, error) {
+	if kubeconfig == "" && master == "" && kubeContext == "" {
+		return rest.InClusterConfig()
+	}
+
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	overrides.ClusterInfo.Server = master
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
 func main() {
 	os.Exit(Main())
 }
@@ -0,0 +1,146 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/render"
+)
+
+// Render implements the render subcommand, which writes the objects the
+// operator would reconcile for a configuration without talking to a cluster.
+func Render(args []string) int {
+	flagset := flag.NewFlagSet("render", flag.ExitOnError)
+	namespace := flagset.String("namespace", "openshift-monitoring", "Namespace the cluster monitoring stack is deployed in.")
+	configFile := flagset.String("config", "", "Path to the config.yaml of the cluster monitoring stack. Defaults are used if not specified.")
+	outputDir := flagset.String("output-dir", "", "Directory to write the objects to, one file per object. The objects are written to stdout if not specified.")
+	platform := flagset.String("platform", "openshift", "Platform to render the objects for: openshift or kubernetes. Objects of OpenShift APIs are not rendered for kubernetes.")
+	tags := tags{}
+	flagset.Var(&tags, "tags", "Tags to use for images.")
+	flagset.Parse(args)
+
+	config, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+	config.SetTagOverrides(tags.asMap())
+
+	switch *platform {
+	case "openshift":
+		config.SetPlatform(manifests.OpenShiftPlatform)
+	case "kubernetes":
+		config.SetPlatform(manifests.KubernetesPlatform)
+	default:
+		fmt.Fprintf(os.Stderr, "invalid value %q for `--platform`, must be one of openshift or kubernetes", *platform)
+		return 1
+	}
+
+	components, err := render.Render(*namespace, config, nil, nil)
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+
+	if *outputDir == "" {
+		err = writeYAML(os.Stdout, components)
+	} else {
+		err = writeDir(*outputDir, components)
+	}
+	if err != nil {
+		fmt.Fprint(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func loadConfig(path string) (*manifests.Config, error) {
+	if path == "" {
+		return manifests.NewDefaultConfig(), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening config failed")
+	}
+	defer f.Close()
+
+	c, err := manifests.NewConfig(f)
+	return c, errors.Wrapf(err, "the config %s is invalid", path)
+}
+
+// writeYAML writes the objects of all components as a single stream of YAML
+// documents.
+func writeYAML(w io.Writer, components []*render.Component) error {
+	for _, c := range components {
+		for _, obj := range c.Objects {
+			b, err := yaml.Marshal(obj)
+			if err != nil {
+				return errors.Wrap(err, "encoding object failed")
+			}
+			if _, err := fmt.Fprintf(w, "---\n%s", b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writeDir writes every object to its own file in a directory per component.
+// The files are prefixed with the position of the object in its component,
+// so that they sort in the order in which they are reconciled.
+func writeDir(dir string, components []*render.Component) error {
+	for _, c := range components {
+		cdir := filepath.Join(dir, c.Name)
+		if err := os.MkdirAll(cdir, 0755); err != nil {
+			return errors.Wrap(err, "creating output directory failed")
+		}
+
+		for i, obj := range c.Objects {
+			b, err := yaml.Marshal(obj)
+			if err != nil {
+				return errors.Wrap(err, "encoding object failed")
+			}
+			name := fmt.Sprintf("%02d-%s.yaml", i, fileName(obj))
+			if err := ioutil.WriteFile(filepath.Join(cdir, name), b, 0644); err != nil {
+				return errors.Wrap(err, "writing object failed")
+			}
+		}
+	}
+	return nil
+}
+
+func fileName(obj runtime.Object) string {
+	kind := strings.ToLower(obj.GetObjectKind().GroupVersionKind().Kind)
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return kind
+	}
+	return kind + "-" + a.GetName()
+}
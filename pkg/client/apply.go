@@ -0,0 +1,479 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/golang/glog"
+	routev1 "github.com/openshift/api/route/v1"
+	secv1 "github.com/openshift/api/security/v1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/retry"
+)
+
+// LastAppliedAnnotation holds the configuration of an object last applied
+// by the operator. It is used to tell the fields the operator stopped
+// setting, which are removed, from the fields set by others, which are
+// left alone. The values of the data of Secrets and ConfigMaps are not
+// recorded, only their keys.
+const LastAppliedAnnotation = "monitoring.openshift.io/last-applied-configuration"
+
+// openshiftScheme resolves the kinds of the OpenShift and Prometheus
+// Operator objects lacking type metadata, the Kubernetes ones being resolved
+// with the client-go scheme.
+var openshiftScheme = runtime.NewScheme()
+
+func init() {
+	for _, add := range []func(*runtime.Scheme) error{
+		routev1.AddToScheme,
+		secv1.AddToScheme,
+		addMonitoringTypes,
+	} {
+		if err := add(openshiftScheme); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// addMonitoringTypes registers the Prometheus Operator types, for which the
+// monitoring client provides no AddToScheme.
+func addMonitoringTypes(s *runtime.Scheme) error {
+	s.AddKnownTypes(schema.GroupVersion{Group: monv1.Group, Version: monv1.Version},
+		&monv1.Prometheus{}, &monv1.PrometheusList{},
+		&monv1.Alertmanager{}, &monv1.AlertmanagerList{},
+		&monv1.ServiceMonitor{}, &monv1.ServiceMonitorList{},
+		&monv1.PrometheusRule{}, &monv1.PrometheusRuleList{},
+	)
+	return nil
+}
+
+// ApplyPolicy tells how Apply reconciles an object.
+type ApplyPolicy int
+
+const (
+	// Reconcile creates the object, or patches it so that it matches.
+	Reconcile ApplyPolicy = iota
+	// CreateOnly creates the object if it does not exist, and leaves it
+	// alone otherwise. It is used for objects holding generated data, such
+	// as passwords, or whose fields are set by the API server, such as the
+	// host of Routes.
+	CreateOnly
+	// ReconcileAndWait reconciles the object and waits for it to be ready,
+	// as told by the readiness checker of its kind, such as the rollout of
+	// Deployments.
+	ReconcileAndWait
+	// Replace creates the object, or replaces it as a whole, dropping the
+	// fields set by others. It is used to regenerate objects holding
+	// generated data, such as rotated secrets.
+	Replace
+)
+
+// Apply reconciles an object of any kind known to the API server according
+// to policy. The resource of the object is discovered from its kind. The
+// object is added to the AppliedSet of ctx, if any.
+func (c *Client) Apply(ctx context.Context, obj runtime.Object, policy ApplyPolicy) error {
+	gvk, err := GroupVersionKindFor(obj)
+	if err != nil {
+		return err
+	}
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	// Objects are recorded even if applying them fails, so that they are
+	// never pruned.
+	recordApplied(ctx, gvk, a.GetNamespace(), a.GetName())
+
+	switch policy {
+	case CreateOnly:
+		err = c.createIfNotExists(ctx, obj)
+	case Reconcile, ReconcileAndWait:
+		_, err = c.apply(ctx, obj)
+	case Replace:
+		err = c.replace(ctx, obj)
+	default:
+		err = errors.Errorf("unknown apply policy %d", policy)
+	}
+	if err != nil || policy != ReconcileAndWait {
+		return err
+	}
+
+	return c.WaitForReady(ctx, obj)
+}
+
+// createIfNotExists creates the object if it does not exist. Its last
+// applied configuration is recorded, so that it can be reconciled later.
+func (c *Client) createIfNotExists(ctx context.Context, desired runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, err := GroupVersionKindFor(desired)
+	if err != nil {
+		return err
+	}
+	modified, err := applyConfiguration(desired, gvk)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: modified}
+
+	ri, err := c.resourceInterface(gvk, obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	_, err = ri.Get(obj.GetName(), metav1.GetOptions{})
+	if !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "retrieving %s object failed", gvk.Kind)
+	}
+
+	created, err := ri.Create(obj)
+	c.recordCreate(desired, created, err)
+	return errors.Wrapf(err, "creating %s object failed", gvk.Kind)
+}
+
+// replace creates the object if it does not exist, and otherwise updates
+// it with the desired object as a whole.
+func (c *Client) replace(ctx context.Context, desired runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, err := GroupVersionKindFor(desired)
+	if err != nil {
+		return err
+	}
+	modified, err := applyConfiguration(desired, gvk)
+	if err != nil {
+		return err
+	}
+	obj := &unstructured.Unstructured{Object: modified}
+
+	ri, err := c.resourceInterface(gvk, obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		live, err := ri.Get(obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			created, err := ri.Create(obj)
+			c.recordCreate(desired, created, err)
+			return errors.Wrapf(err, "creating %s object failed", gvk.Kind)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "retrieving %s object failed", gvk.Kind)
+		}
+
+		obj.SetResourceVersion(live.GetResourceVersion())
+		result, err := ri.Update(obj)
+		if apierrors.IsConflict(err) {
+			glog.V(4).Infof("%s %s changed while being replaced, retrying", gvk.Kind, nameOf(live))
+			return err
+		}
+		c.recordUpdate(desired, result, live.GetResourceVersion(), err)
+		return errors.Wrapf(err, "replacing %s object failed", gvk.Kind)
+	})
+}
+
+// apply creates the object if it does not exist. Otherwise it patches the
+// live object with a three-way merge of the last applied configuration,
+// the desired object and the live object: the fields of the desired object
+// that differ are set, the fields that were last applied but are not
+// desired anymore are removed, and all other fields are kept. The patch is
+// conditional on the resource version of the live object, and is computed
+// again if the object changed in the meantime.
+func (c *Client) apply(ctx context.Context, desired runtime.Object) (*unstructured.Unstructured, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	gvk, err := GroupVersionKindFor(desired)
+	if err != nil {
+		return nil, err
+	}
+	modified, err := applyConfiguration(desired, gvk)
+	if err != nil {
+		return nil, err
+	}
+	obj := &unstructured.Unstructured{Object: modified}
+
+	ri, err := c.resourceInterface(gvk, obj.GetNamespace())
+	if err != nil {
+		return nil, err
+	}
+
+	var result *unstructured.Unstructured
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		live, err := ri.Get(obj.GetName(), metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result, err = ri.Create(obj)
+			c.recordCreate(desired, result, err)
+			return errors.Wrapf(err, "creating %s object failed", gvk.Kind)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "retrieving %s object failed", gvk.Kind)
+		}
+
+		var original map[string]interface{}
+		if la, ok := live.GetAnnotations()[LastAppliedAnnotation]; ok {
+			if err := json.Unmarshal([]byte(la), &original); err != nil {
+				glog.V(4).Infof("ignoring invalid last applied configuration of %s %s: %v", gvk.Kind, nameOf(live), err)
+				original = nil
+			}
+		}
+
+		patch := threeWayMergePatch(original, modified, live.Object)
+		if len(patch) == 0 {
+			result = live
+			return nil
+		}
+		if err := unstructured.SetNestedField(patch, live.GetResourceVersion(), "metadata", "resourceVersion"); err != nil {
+			return err
+		}
+		data, err := json.Marshal(patch)
+		if err != nil {
+			return errors.Wrap(err, "encoding patch failed")
+		}
+
+		result, err = ri.Patch(obj.GetName(), types.MergePatchType, data)
+		if apierrors.IsConflict(err) {
+			glog.V(4).Infof("%s %s changed while being patched, retrying", gvk.Kind, nameOf(live))
+			return err
+		}
+		c.recordUpdate(desired, result, live.GetResourceVersion(), err)
+		return errors.Wrapf(err, "patching %s object failed", gvk.Kind)
+	})
+
+	return result, err
+}
+
+// GroupVersionKindFor returns the kind of obj. The kind registered for the
+// type of typed objects takes precedence over their type metadata, which may
+// hold a legacy API version, such as v1 for Routes.
+func GroupVersionKindFor(obj runtime.Object) (schema.GroupVersionKind, error) {
+	if _, ok := obj.(*unstructured.Unstructured); !ok {
+		for _, s := range []*runtime.Scheme{scheme.Scheme, openshiftScheme} {
+			if gvks, _, err := s.ObjectKinds(obj); err == nil && len(gvks) > 0 {
+				return gvks[0], nil
+			}
+		}
+	}
+
+	if gvk := obj.GetObjectKind().GroupVersionKind(); !gvk.Empty() {
+		return gvk, nil
+	}
+	return schema.GroupVersionKind{}, errors.Errorf("kind of %s object unknown", kindOf(obj))
+}
+
+// applyConfiguration returns the fields of desired to apply, including the
+// last applied configuration annotation. Fields populated by the API
+// server, the status and unset fields are left out.
+func applyConfiguration(desired runtime.Object, gvk schema.GroupVersionKind) (map[string]interface{}, error) {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, errors.Wrapf(err, "converting %s object failed", gvk.Kind)
+	}
+	m = pruneNulls(m)
+	delete(m, "status")
+	m["apiVersion"], m["kind"] = gvk.GroupVersion().String(), gvk.Kind
+
+	obj := &unstructured.Unstructured{Object: m}
+	for _, f := range []string{"creationTimestamp", "generation", "resourceVersion", "selfLink", "uid"} {
+		unstructured.RemoveNestedField(m, "metadata", f)
+	}
+	annotations := obj.GetAnnotations()
+	delete(annotations, LastAppliedAnnotation)
+	obj.SetAnnotations(annotations)
+
+	la, err := json.Marshal(withoutDataValues(m, gvk))
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding last applied configuration failed")
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[LastAppliedAnnotation] = string(la)
+	obj.SetAnnotations(annotations)
+
+	return m, nil
+}
+
+// withoutDataValues returns a copy of the configuration of a Secret or a
+// ConfigMap with the values of its data blanked, so that secrets are not
+// revealed and large ConfigMaps fit in an annotation. The keys are enough
+// to tell the data that was removed.
+func withoutDataValues(m map[string]interface{}, gvk schema.GroupVersionKind) map[string]interface{} {
+	if gvk.Group != "" || (gvk.Kind != "Secret" && gvk.Kind != "ConfigMap") {
+		return m
+	}
+
+	res := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		res[k] = v
+	}
+	for _, f := range []string{"data", "stringData", "binaryData"} {
+		data, ok := m[f].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		blanked := make(map[string]interface{}, len(data))
+		for k := range data {
+			blanked[k] = ""
+		}
+		res[f] = blanked
+	}
+	return res
+}
+
+// pruneNulls removes the null values, which merge patches interpret as
+// deletions.
+func pruneNulls(m map[string]interface{}) map[string]interface{} {
+	for k, v := range m {
+		switch t := v.(type) {
+		case nil:
+			delete(m, k)
+		case map[string]interface{}:
+			pruneNulls(t)
+		case []interface{}:
+			for _, e := range t {
+				if em, ok := e.(map[string]interface{}); ok {
+					pruneNulls(em)
+				}
+			}
+		}
+	}
+	return m
+}
+
+// threeWayMergePatch returns the JSON merge patch turning current into an
+// object with the fields of modified, without the fields of original that
+// are not in modified, and with all other fields of current unchanged.
+// Lists are replaced as a whole if they differ from current, or from
+// original, so that the fields removed from their elements are removed as
+// well. The patch is empty if current already matches.
+func threeWayMergePatch(original, modified, current map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+
+	for k, m := range modified {
+		c, ok := current[k]
+		if mm, isMap := m.(map[string]interface{}); isMap {
+			cm, isMap := c.(map[string]interface{})
+			if !ok || !isMap {
+				patch[k] = m
+				continue
+			}
+			om, _ := original[k].(map[string]interface{})
+			if sub := threeWayMergePatch(om, mm, cm); len(sub) > 0 {
+				patch[k] = sub
+			}
+			continue
+		}
+		if !ok || !contains(c, m) {
+			patch[k] = m
+			continue
+		}
+		if _, isList := m.([]interface{}); isList {
+			// The elements of current may have more fields than
+			// the ones of modified, such as defaults, so fields
+			// removed from them are only told apart by original.
+			if o, ok := original[k]; ok && !(contains(o, m) && contains(m, o)) {
+				patch[k] = m
+			}
+		}
+	}
+
+	for k := range original {
+		if _, ok := modified[k]; ok {
+			continue
+		}
+		if _, ok := current[k]; ok {
+			patch[k] = nil
+		}
+	}
+
+	return patch
+}
+
+// contains returns true if current has all the fields of desired with the
+// same values. Fields only set in current, such as defaults, are ignored.
+func contains(current, desired interface{}) bool {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		c, ok := current.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range d {
+			cv, ok := c[k]
+			if !ok || !contains(cv, v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		c, ok := current.([]interface{})
+		if !ok || len(c) != len(d) {
+			return false
+		}
+		for i := range d {
+			if !contains(c[i], d[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if fd, ok := toFloat(desired); ok {
+		fc, ok := toFloat(current)
+		return ok && fc == fd
+	}
+	return reflect.DeepEqual(current, desired)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
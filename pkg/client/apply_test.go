@@ -0,0 +1,139 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func decode(t *testing.T, s string) map[string]interface{} {
+	if s == "" {
+		return nil
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestThreeWayMergePatch(t *testing.T) {
+	cases := []struct {
+		name     string
+		original string
+		modified string
+		current  string
+		patch    string
+	}{
+		{
+			name:     "fields set by others are kept",
+			original: `{"spec":{"type":"ClusterIP"}}`,
+			modified: `{"spec":{"type":"ClusterIP"}}`,
+			current:  `{"metadata":{"annotations":{"other":"true"}},"spec":{"type":"ClusterIP","clusterIP":"10.0.0.1"}}`,
+			patch:    `{}`,
+		}, {
+			name:     "changed fields are set",
+			original: `{"spec":{"replicas":1,"paused":false}}`,
+			modified: `{"spec":{"replicas":2,"paused":false}}`,
+			current:  `{"spec":{"replicas":1,"paused":false,"revisionHistoryLimit":10}}`,
+			patch:    `{"spec":{"replicas":2}}`,
+		}, {
+			name:     "fields not applied anymore are removed",
+			original: `{"metadata":{"labels":{"a":"1","b":"2"}}}`,
+			modified: `{"metadata":{"labels":{"a":"1"}}}`,
+			current:  `{"metadata":{"labels":{"a":"1","b":"2","c":"3"}}}`,
+			patch:    `{"metadata":{"labels":{"b":null}}}`,
+		}, {
+			name:     "fields of objects not applied before are not removed",
+			modified: `{"metadata":{"labels":{"a":"1"}}}`,
+			current:  `{"metadata":{"labels":{"a":"1","b":"2"}}}`,
+			patch:    `{}`,
+		}, {
+			name:     "lists with defaulted fields are equal",
+			original: `{"spec":{"ports":[{"name":"web","port":9090}]}}`,
+			modified: `{"spec":{"ports":[{"name":"web","port":9090}]}}`,
+			current:  `{"spec":{"ports":[{"name":"web","port":9090,"protocol":"TCP","targetPort":9090}]}}`,
+			patch:    `{}`,
+		}, {
+			name:     "lists are replaced",
+			original: `{"spec":{"ports":[{"name":"web","port":9090}]}}`,
+			modified: `{"spec":{"ports":[{"name":"web","port":9091}]}}`,
+			current:  `{"spec":{"ports":[{"name":"web","port":9090,"protocol":"TCP"}]}}`,
+			patch:    `{"spec":{"ports":[{"name":"web","port":9091}]}}`,
+		}, {
+			name:     "lists with fields removed from their elements are replaced",
+			original: `{"spec":{"containers":[{"name":"prometheus","resources":{"limits":{"memory":"2Gi"}}}]}}`,
+			modified: `{"spec":{"containers":[{"name":"prometheus"}]}}`,
+			current:  `{"spec":{"containers":[{"name":"prometheus","resources":{"limits":{"memory":"2Gi"}},"terminationMessagePath":"/dev/termination-log"}]}}`,
+			patch:    `{"spec":{"containers":[{"name":"prometheus"}]}}`,
+		}, {
+			name:     "lists applied unchanged are not replaced",
+			original: `{"spec":{"remoteWrite":[{"url":"https://example.com","basicAuth":{"username":{"name":"a","key":"user"}}}]}}`,
+			modified: `{"spec":{"remoteWrite":[{"url":"https://example.com","basicAuth":{"username":{"name":"a","key":"user"}}}]}}`,
+			current:  `{"spec":{"remoteWrite":[{"url":"https://example.com","basicAuth":{"username":{"name":"a","key":"user"}},"remoteTimeout":"30s"}]}}`,
+			patch:    `{}`,
+		}, {
+			name:     "missing maps are set",
+			modified: `{"spec":{"selector":{"app":"grafana"}}}`,
+			current:  `{"spec":{}}`,
+			patch:    `{"spec":{"selector":{"app":"grafana"}}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		patch := threeWayMergePatch(decode(t, tc.original), decode(t, tc.modified), decode(t, tc.current))
+		if expected := decode(t, tc.patch); !reflect.DeepEqual(patch, expected) {
+			t.Errorf("%s: expected patch %v, got %v", tc.name, expected, patch)
+		}
+	}
+}
+
+func TestApplyConfiguration(t *testing.T) {
+	s := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "grafana-datasources",
+			Namespace:       "openshift-monitoring",
+			ResourceVersion: "42",
+		},
+		Data: map[string][]byte{"prometheus.yaml": []byte("secret")},
+	}
+
+	m, err := applyConfiguration(s, v1.SchemeGroupVersion.WithKind("Secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := m["metadata"].(map[string]interface{})
+	if _, ok := metadata["resourceVersion"]; ok {
+		t.Error("expected the resource version to be left out")
+	}
+	if _, ok := metadata["creationTimestamp"]; ok {
+		t.Error("expected unset fields to be left out")
+	}
+	if m["kind"] != "Secret" || m["apiVersion"] != "v1" {
+		t.Errorf("expected the kind to be set, got %v %v", m["apiVersion"], m["kind"])
+	}
+
+	la := metadata["annotations"].(map[string]interface{})[LastAppliedAnnotation].(string)
+	data := decode(t, la)["data"]
+	if expected := map[string]interface{}{"prometheus.yaml": ""}; !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected the data values to be blanked in the last applied configuration, got %v", data)
+	}
+}
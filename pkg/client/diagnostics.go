@@ -0,0 +1,228 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// maxDiagnosticEvents is the number of most recent events a RolloutError
+// lists.
+const maxDiagnosticEvents = 5
+
+// RolloutError is returned when an object does not become ready in time. It
+// tells why from the state of the pods of the object, their
+// PersistentVolumeClaims and the recent warning events about them.
+type RolloutError struct {
+	// Err is the error the wait failed with.
+	Err error
+	// Pods describes the pods of the object that are not ready.
+	Pods []string
+	// UnboundClaims describes the PersistentVolumeClaims of these pods
+	// that are not bound.
+	UnboundClaims []string
+	// Events describes the recent warning events about the object, its
+	// pods and their PersistentVolumeClaims, the most recent first.
+	Events []string
+}
+
+func (e *RolloutError) Error() string {
+	parts := []string{e.Err.Error()}
+	if len(e.Pods) > 0 {
+		parts = append(parts, "pods not ready: "+strings.Join(e.Pods, ", "))
+	}
+	if len(e.UnboundClaims) > 0 {
+		parts = append(parts, "unbound PersistentVolumeClaims: "+strings.Join(e.UnboundClaims, ", "))
+	}
+	if len(e.Events) > 0 {
+		parts = append(parts, "recent events: "+strings.Join(e.Events, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Cause returns the error the wait failed with, so that errors.Cause
+// returns it for a RolloutError.
+func (e *RolloutError) Cause() error {
+	return e.Err
+}
+
+// diagnose returns a RolloutError explaining why live, with pods matching
+// selector, did not become ready. selector may be nil for objects without
+// pods. Diagnostics that cannot be retrieved are left out.
+func (c *Client) diagnose(live *unstructured.Unstructured, selector labels.Selector, cause error) error {
+	re := &RolloutError{Err: cause}
+	if live == nil {
+		return re
+	}
+	ns := live.GetNamespace()
+	involved := map[string]bool{live.GetKind() + "/" + live.GetName(): true}
+
+	if selector != nil {
+		pods, err := c.kclient.CoreV1().Pods(ns).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			glog.V(4).Infof("listing pods of %s %s/%s failed: %v", live.GetKind(), ns, live.GetName(), err)
+			pods = &v1.PodList{}
+		}
+
+		for _, p := range pods.Items {
+			if podReady(&p) {
+				continue
+			}
+			re.Pods = append(re.Pods, describePod(&p))
+			involved["Pod/"+p.Name] = true
+
+			for _, claim := range claimsOf(&p) {
+				pvc, err := c.kclient.CoreV1().PersistentVolumeClaims(ns).Get(claim, metav1.GetOptions{})
+				switch {
+				case apierrors.IsNotFound(err):
+					re.UnboundClaims = append(re.UnboundClaims, claim+" (not found)")
+				case err != nil:
+					glog.V(4).Infof("retrieving PersistentVolumeClaim %s/%s failed: %v", ns, claim, err)
+				case pvc.Status.Phase != v1.ClaimBound:
+					re.UnboundClaims = append(re.UnboundClaims, fmt.Sprintf("%s (%s)", claim, pvc.Status.Phase))
+					involved["PersistentVolumeClaim/"+claim] = true
+				}
+			}
+		}
+	}
+
+	events, err := c.kclient.CoreV1().Events(ns).List(metav1.ListOptions{})
+	if err != nil {
+		glog.V(4).Infof("listing events in namespace %s failed: %v", ns, err)
+		return re
+	}
+	re.Events = recentWarnings(events.Items, involved)
+
+	return re
+}
+
+// podReady returns true if the Ready condition of the pod is true.
+func podReady(p *v1.Pod) bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == v1.PodReady {
+			return c.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// describePod describes why a pod is not ready, such as that it cannot be
+// scheduled or that its containers are waiting or were terminated.
+func describePod(p *v1.Pod) string {
+	details := []string{}
+	for _, c := range p.Status.Conditions {
+		if c.Type == v1.PodScheduled && c.Status == v1.ConditionFalse {
+			details = append(details, "not scheduled"+reasonAndMessage(c.Reason, c.Message))
+		}
+	}
+
+	statuses := append(append([]v1.ContainerStatus{}, p.Status.InitContainerStatuses...), p.Status.ContainerStatuses...)
+	for _, cs := range statuses {
+		if cs.Ready {
+			continue
+		}
+		switch s := cs.State; {
+		case s.Waiting != nil:
+			d := fmt.Sprintf("container %s waiting%s", cs.Name, reasonAndMessage(s.Waiting.Reason, s.Waiting.Message))
+			if t := cs.LastTerminationState.Terminated; t != nil {
+				d += fmt.Sprintf(", last terminated%s", terminationReason(t))
+			}
+			details = append(details, d)
+		case s.Terminated != nil:
+			details = append(details, fmt.Sprintf("container %s terminated%s", cs.Name, terminationReason(s.Terminated)))
+		case s.Running != nil:
+			details = append(details, fmt.Sprintf("container %s running but not ready", cs.Name))
+		}
+	}
+
+	d := fmt.Sprintf("%s (%s)", p.Name, p.Status.Phase)
+	if len(details) > 0 {
+		d += ": " + strings.Join(details, ", ")
+	}
+	return d
+}
+
+func reasonAndMessage(reason, message string) string {
+	switch {
+	case reason != "" && message != "":
+		return fmt.Sprintf(" (%s: %s)", reason, message)
+	case reason != "":
+		return fmt.Sprintf(" (%s)", reason)
+	case message != "":
+		return fmt.Sprintf(" (%s)", message)
+	}
+	return ""
+}
+
+func terminationReason(t *v1.ContainerStateTerminated) string {
+	reason := t.Reason
+	if reason == "" {
+		reason = "Terminated"
+	}
+	return fmt.Sprintf(" (%s, exit code %d)", reason, t.ExitCode)
+}
+
+// claimsOf returns the names of the PersistentVolumeClaims mounted by the
+// pod.
+func claimsOf(p *v1.Pod) []string {
+	claims := []string{}
+	for _, v := range p.Spec.Volumes {
+		if v.PersistentVolumeClaim != nil {
+			claims = append(claims, v.PersistentVolumeClaim.ClaimName)
+		}
+	}
+	return claims
+}
+
+// recentWarnings describes the most recent warning events about the
+// involved objects, given as kind/name. Events recorded by the operator
+// itself are left out, as they repeat the errors it returned.
+func recentWarnings(events []v1.Event, involved map[string]bool) []string {
+	matching := []v1.Event{}
+	for _, e := range events {
+		if e.Type != v1.EventTypeWarning || e.Source.Component == eventSourceComponent {
+			continue
+		}
+		if involved[e.InvolvedObject.Kind+"/"+e.InvolvedObject.Name] {
+			matching = append(matching, e)
+		}
+	}
+
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[j].LastTimestamp.Before(&matching[i].LastTimestamp)
+	})
+	if len(matching) > maxDiagnosticEvents {
+		matching = matching[:maxDiagnosticEvents]
+	}
+
+	res := []string{}
+	for _, e := range matching {
+		d := fmt.Sprintf("%s %s: %s: %s", e.InvolvedObject.Kind, e.InvolvedObject.Name, e.Reason, e.Message)
+		if e.Count > 1 {
+			d += fmt.Sprintf(" (x%d)", e.Count)
+		}
+		res = append(res, d)
+	}
+	return res
+}
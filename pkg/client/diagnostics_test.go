@@ -0,0 +1,157 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestDescribePod(t *testing.T) {
+	cases := []struct {
+		name     string
+		status   v1.PodStatus
+		expected string
+	}{
+		{
+			name: "unschedulable",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				Conditions: []v1.PodCondition{{
+					Type:    v1.PodScheduled,
+					Status:  v1.ConditionFalse,
+					Reason:  "Unschedulable",
+					Message: "0/3 nodes are available: 3 Insufficient memory.",
+				}},
+			},
+			expected: "p (Pending): not scheduled (Unschedulable: 0/3 nodes are available: 3 Insufficient memory.)",
+		}, {
+			name: "image pull failing",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name: "grafana",
+					State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{
+						Reason:  "ImagePullBackOff",
+						Message: `Back-off pulling image "grafana:nope"`,
+					}},
+				}},
+			},
+			expected: `p (Pending): container grafana waiting (ImagePullBackOff: Back-off pulling image "grafana:nope")`,
+		}, {
+			name: "crash looping",
+			status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				ContainerStatuses: []v1.ContainerStatus{{
+					Name:  "proxy",
+					Ready: true,
+				}, {
+					Name:                 "prometheus",
+					State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}},
+				}},
+			},
+			expected: "p (Running): container prometheus waiting (CrashLoopBackOff), last terminated (Error, exit code 1)",
+		}, {
+			name: "init container terminated",
+			status: v1.PodStatus{
+				Phase: v1.PodPending,
+				InitContainerStatuses: []v1.ContainerStatus{{
+					Name:  "init",
+					State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 2}},
+				}},
+			},
+			expected: "p (Pending): container init terminated (Terminated, exit code 2)",
+		},
+	}
+
+	for _, tc := range cases {
+		p := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "p"}, Status: tc.status}
+		if got := describePod(p); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestRecentWarnings(t *testing.T) {
+	now := time.Now()
+	event := func(kind, name, reason string, age time.Duration) v1.Event {
+		return v1.Event{
+			InvolvedObject: v1.ObjectReference{Kind: kind, Name: name},
+			Type:           v1.EventTypeWarning,
+			Reason:         reason,
+			Message:        "m",
+			Count:          1,
+			LastTimestamp:  metav1.NewTime(now.Add(-age)),
+		}
+	}
+
+	normal := event("Pod", "p", "Pulled", 0)
+	normal.Type = v1.EventTypeNormal
+	own := event("Deployment", "d", reasonRolloutFailed, 0)
+	own.Source.Component = eventSourceComponent
+	repeated := event("Pod", "p", "BackOff", time.Minute)
+	repeated.Count = 7
+
+	events := []v1.Event{
+		event("Pod", "p", "FailedScheduling", time.Hour),
+		normal,
+		own,
+		event("Pod", "other", "Failed", 0),
+		repeated,
+		event("PersistentVolumeClaim", "c", "ProvisioningFailed", 2*time.Minute),
+	}
+	involved := map[string]bool{"Deployment/d": true, "Pod/p": true, "PersistentVolumeClaim/c": true}
+
+	expected := []string{
+		"Pod p: BackOff: m (x7)",
+		"PersistentVolumeClaim c: ProvisioningFailed: m",
+		"Pod p: FailedScheduling: m",
+	}
+	if got := recentWarnings(events, involved); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestRolloutError(t *testing.T) {
+	err := errors.Wrap(&RolloutError{
+		Err:           wait.ErrWaitTimeout,
+		Pods:          []string{"alertmanager-main-0 (Pending)"},
+		UnboundClaims: []string{"alertmanager-main-db-alertmanager-main-0 (Pending)"},
+	}, "waiting for Alertmanager object changes failed")
+
+	if errors.Cause(err) != wait.ErrWaitTimeout {
+		t.Errorf("expected the cause to be the wait error, got %v", errors.Cause(err))
+	}
+	for _, s := range []string{
+		"timed out waiting for the condition",
+		"pods not ready: alertmanager-main-0 (Pending)",
+		"unbound PersistentVolumeClaims: alertmanager-main-db-alertmanager-main-0 (Pending)",
+	} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("expected error to contain %q, got %q", s, err)
+		}
+	}
+	if strings.Contains(err.Error(), "recent events") {
+		t.Errorf("expected no events to be listed, got %q", err)
+	}
+}
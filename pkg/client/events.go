@@ -0,0 +1,123 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"reflect"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	eventSourceComponent = "cluster-monitoring-operator"
+
+	reasonCreated       = "Created"
+	reasonCreateFailed  = "CreateFailed"
+	reasonUpdated       = "Updated"
+	reasonUpdateFailed  = "UpdateFailed"
+	reasonDeleted       = "Deleted"
+	reasonDeleteFailed  = "DeleteFailed"
+	reasonRolloutFailed = "RolloutFailed"
+	reasonRouteNotReady = "RouteNotReady"
+	reasonRestarted     = "Restarted"
+
+	reasonLoadBalancerNotReady = "LoadBalancerNotReady"
+)
+
+// recordCreate records an event about the creation of desired. result is the
+// object returned by the API server, used to reference the created object.
+func (c *Client) recordCreate(desired, result runtime.Object, err error) {
+	if err != nil {
+		c.eventf(desired, v1.EventTypeWarning, reasonCreateFailed, "Creating %s %s failed: %v", kindOf(desired), nameOf(desired), err)
+		return
+	}
+	c.eventf(withKind(result, desired), v1.EventTypeNormal, reasonCreated, "Created %s %s", kindOf(desired), nameOf(desired))
+}
+
+// recordUpdate records an event about the update of desired. No event is
+// recorded if the update did not change the object, that is if the resource
+// version of result is previousResourceVersion.
+func (c *Client) recordUpdate(desired, result runtime.Object, previousResourceVersion string, err error) {
+	if err != nil {
+		c.eventf(desired, v1.EventTypeWarning, reasonUpdateFailed, "Updating %s %s failed: %v", kindOf(desired), nameOf(desired), err)
+		return
+	}
+	if a, err := meta.Accessor(result); err == nil && previousResourceVersion != "" && a.GetResourceVersion() == previousResourceVersion {
+		return
+	}
+	c.eventf(withKind(result, desired), v1.EventTypeNormal, reasonUpdated, "Updated %s %s", kindOf(desired), nameOf(desired))
+}
+
+// recordWaitFailed records a warning event about obj if waiting for it to
+// become ready failed.
+func (c *Client) recordWaitFailed(obj runtime.Object, reason string, err error) {
+	if err == nil {
+		return
+	}
+	c.eventf(obj, v1.EventTypeWarning, reason, "Waiting for %s %s failed: %v", kindOf(obj), nameOf(obj), err)
+}
+
+func (c *Client) eventf(obj runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	if c.events == nil || isNil(obj) {
+		return
+	}
+	c.events.Eventf(obj, eventtype, reason, messageFmt, args...)
+}
+
+// withKind returns result with the kind of desired if result has none, as
+// typed clients do not set the kind of the objects they return and not all
+// of the managed types are known to the scheme used to build references.
+func withKind(result, desired runtime.Object) runtime.Object {
+	if isNil(result) {
+		return desired
+	}
+	if !result.GetObjectKind().GroupVersionKind().Empty() || isNil(desired) {
+		return result
+	}
+	result = result.DeepCopyObject()
+	result.GetObjectKind().SetGroupVersionKind(desired.GetObjectKind().GroupVersionKind())
+	return result
+}
+
+func kindOf(obj runtime.Object) string {
+	if isNil(obj) {
+		return ""
+	}
+	if k := obj.GetObjectKind().GroupVersionKind().Kind; k != "" {
+		return k
+	}
+	return reflect.Indirect(reflect.ValueOf(obj)).Type().Name()
+}
+
+func nameOf(obj runtime.Object) string {
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	if a.GetNamespace() == "" {
+		return a.GetName()
+	}
+	return a.GetNamespace() + "/" + a.GetName()
+}
+
+func isNil(obj runtime.Object) bool {
+	if obj == nil {
+		return true
+	}
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,442 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fake provides an in-memory implementation of client.Interface to
+// test the reconciliation tasks without an API server.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+)
+
+// RouterDomain is the domain of the hosts given to admitted Routes that do
+// not set one, as the OpenShift router does.
+const RouterDomain = "apps.example.com"
+
+// LoadBalancerIP is the IP address given to provisioned load balancers.
+const LoadBalancerIP = "192.0.2.1"
+
+// Verbs of the actions recorded by the Client.
+const (
+	VerbCreate  = "create"
+	VerbUpdate  = "update"
+	VerbGet     = "get"
+	VerbWait    = "wait"
+	VerbRestart = "restart"
+)
+
+// Action is an operation the Client performed on an object.
+type Action struct {
+	Verb      string
+	Kind      string
+	Namespace string
+	Name      string
+}
+
+func (a Action) String() string {
+	if a.Namespace == "" {
+		return fmt.Sprintf("%s %s %s", a.Verb, a.Kind, a.Name)
+	}
+	return fmt.Sprintf("%s %s %s/%s", a.Verb, a.Kind, a.Namespace, a.Name)
+}
+
+type key struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// Client is an in-memory client.Interface. Applied objects are stored, and
+// their status is simulated as the controllers of a cluster would set it:
+// workloads are rolled out, Routes are admitted and load balancers are
+// provisioned right away, unless SetNotReady is called for them. Objects are
+// told apart by kind, namespace and name, the API groups of kinds are
+// ignored.
+type Client struct {
+	namespace string
+
+	mtx      sync.Mutex
+	objects  map[key]*unstructured.Unstructured
+	notReady map[key]bool
+	actions  []Action
+}
+
+var _ client.Interface = &Client{}
+
+// NewClient returns a Client for the given namespace, holding the given
+// objects. It panics if the kind of an object is unknown.
+func NewClient(namespace string, objects ...runtime.Object) *Client {
+	c := &Client{
+		namespace: namespace,
+		objects:   map[key]*unstructured.Unstructured{},
+		notReady:  map[key]bool{},
+	}
+
+	for _, obj := range objects {
+		k, u, err := toUnstructured(obj)
+		if err != nil {
+			panic(err)
+		}
+		c.objects[k] = c.withStatus(k, u)
+	}
+	return c
+}
+
+// SetNotReady makes the object of the given kind, namespace and name never
+// become ready: workloads do not finish rolling out, Routes are not
+// admitted and load balancers are not provisioned.
+func (c *Client) SetNotReady(kind, namespace, name string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	k := key{kind, namespace, name}
+	c.notReady[k] = true
+	if u, ok := c.objects[k]; ok {
+		c.objects[k] = c.withStatus(k, u)
+	}
+}
+
+// Actions returns the actions performed since the Client was created or
+// the actions were last cleared, in order.
+func (c *Client) Actions() []Action {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]Action{}, c.actions...)
+}
+
+// ClearActions forgets the actions performed so far.
+func (c *Client) ClearActions() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.actions = nil
+}
+
+func (c *Client) record(verb string, k key) {
+	c.actions = append(c.actions, Action{Verb: verb, Kind: k.kind, Namespace: k.namespace, Name: k.name})
+}
+
+func (c *Client) Namespace() string {
+	return c.namespace
+}
+
+// Apply creates obj if it does not exist, and otherwise updates it if it
+// differs from the stored object, unless policy is client.CreateOnly.
+// Updates keep the annotations of the stored object, as the three-way merge
+// of the Client does, unless policy is client.Replace.
+func (c *Client) Apply(ctx context.Context, obj runtime.Object, policy client.ApplyPolicy) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	k, desired, err := toUnstructured(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	current, exists := c.objects[k]
+	switch {
+	case !exists:
+		c.objects[k] = c.withStatus(k, desired)
+		c.record(VerbCreate, k)
+	case policy == client.CreateOnly:
+	default:
+		if policy != client.Replace {
+			desired = withAnnotationsOf(desired, current)
+		}
+		if !reflect.DeepEqual(withoutStatus(current), withoutStatus(desired)) {
+			c.objects[k] = c.withStatus(k, desired)
+			c.record(VerbUpdate, k)
+		}
+	}
+	c.mtx.Unlock()
+
+	if policy == client.ReconcileAndWait {
+		return c.WaitForReady(ctx, obj)
+	}
+	return nil
+}
+
+// Get retrieves the stored object of the kind, namespace and name of obj
+// into obj.
+func (c *Client) Get(ctx context.Context, obj runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, k, err := keyFor(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	c.record(VerbGet, k)
+	u, ok := c.objects[k]
+	c.mtx.Unlock()
+	if !ok {
+		return notFound(gvk, k)
+	}
+
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(u.DeepCopy().Object, obj)
+}
+
+// Restart sets client.RestartedAtAnnotation on the stored object, and
+// returns wait.ErrWaitTimeout right away if it is not ready.
+func (c *Client) Restart(ctx context.Context, obj runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, k, err := keyFor(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.record(VerbRestart, k)
+	u, ok := c.objects[k]
+	if !ok {
+		return notFound(gvk, k)
+	}
+
+	u = u.DeepCopy()
+	a := u.GetAnnotations()
+	if a == nil {
+		a = map[string]string{}
+	}
+	a[client.RestartedAtAnnotation] = fmt.Sprintf("%d", len(c.actions))
+	u.SetAnnotations(a)
+	c.objects[k] = u
+
+	if !ready(u) {
+		return wait.ErrWaitTimeout
+	}
+	return nil
+}
+
+// WaitForReady returns wait.ErrWaitTimeout right away if the stored object
+// is not ready, as there is nobody to make it ready in the meantime.
+func (c *Client) WaitForReady(ctx context.Context, obj runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, k, err := keyFor(obj)
+	if err != nil {
+		return err
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.record(VerbWait, k)
+	u, ok := c.objects[k]
+	if !ok {
+		return notFound(gvk, k)
+	}
+
+	if !ready(u) {
+		return wait.ErrWaitTimeout
+	}
+	return nil
+}
+
+func (c *Client) WaitForRouteReady(ctx context.Context, r *routev1.Route) (string, error) {
+	if err := c.WaitForReady(ctx, r); err != nil {
+		return "", err
+	}
+
+	live := &routev1.Route{}
+	live.SetNamespace(r.GetNamespace())
+	live.SetName(r.GetName())
+	if err := c.Get(ctx, live); err != nil {
+		return "", err
+	}
+	return live.Spec.Host, nil
+}
+
+// WaitForPrometheusOperatorCRDsReady returns right away, the Client serving
+// objects of any kind.
+func (c *Client) WaitForPrometheusOperatorCRDsReady(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func keyFor(obj runtime.Object) (schema.GroupVersionKind, key, error) {
+	gvk, err := client.GroupVersionKindFor(obj)
+	if err != nil {
+		return gvk, key{}, err
+	}
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return gvk, key{}, err
+	}
+	return gvk, key{gvk.Kind, a.GetNamespace(), a.GetName()}, nil
+}
+
+func toUnstructured(obj runtime.Object) (key, *unstructured.Unstructured, error) {
+	gvk, k, err := keyFor(obj)
+	if err != nil {
+		return k, nil, err
+	}
+
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return k, nil, errors.Wrapf(err, "converting %s object failed", gvk.Kind)
+	}
+	u := &unstructured.Unstructured{Object: m}
+	u.SetGroupVersionKind(gvk)
+	return k, u, nil
+}
+
+func notFound(gvk schema.GroupVersionKind, k key) error {
+	return apierrors.NewNotFound(schema.GroupResource{Group: gvk.Group, Resource: strings.ToLower(k.kind) + "s"}, k.name)
+}
+
+// withAnnotationsOf returns a copy of desired with the annotations of current
+// it does not set.
+func withAnnotationsOf(desired, current *unstructured.Unstructured) *unstructured.Unstructured {
+	if len(current.GetAnnotations()) == 0 {
+		return desired
+	}
+
+	desired = desired.DeepCopy()
+	a := desired.GetAnnotations()
+	if a == nil {
+		a = map[string]string{}
+	}
+	for k, v := range current.GetAnnotations() {
+		if _, ok := a[k]; !ok {
+			a[k] = v
+		}
+	}
+	desired.SetAnnotations(a)
+	return desired
+}
+
+func withoutStatus(u *unstructured.Unstructured) map[string]interface{} {
+	m := u.DeepCopy().Object
+	delete(m, "status")
+	return m
+}
+
+// withStatus returns a copy of u with the status the controllers of a
+// cluster would eventually set.
+func (c *Client) withStatus(k key, u *unstructured.Unstructured) *unstructured.Unstructured {
+	u = u.DeepCopy()
+	ready := !c.notReady[k]
+
+	switch k.kind {
+	case "Deployment", "StatefulSet", "Prometheus", "Alertmanager":
+		replicas, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+		if !found {
+			replicas = 1
+		}
+		updated := replicas
+		if !ready {
+			updated = 0
+		}
+		unstructured.SetNestedField(u.Object, map[string]interface{}{
+			"replicas":            replicas,
+			"updatedReplicas":     updated,
+			"readyReplicas":       updated,
+			"availableReplicas":   updated,
+			"unavailableReplicas": replicas - updated,
+		}, "status")
+	case "DaemonSet":
+		// A DaemonSet is simulated to run on a single node.
+		updated := int64(1)
+		if !ready {
+			updated = 0
+		}
+		unstructured.SetNestedField(u.Object, map[string]interface{}{
+			"desiredNumberScheduled": int64(1),
+			"updatedNumberScheduled": updated,
+			"numberAvailable":        updated,
+			"numberUnavailable":      1 - updated,
+		}, "status")
+	case "Route":
+		host, _, _ := unstructured.NestedString(u.Object, "spec", "host")
+		if host == "" {
+			host = fmt.Sprintf("%s-%s.%s", k.name, k.namespace, RouterDomain)
+			unstructured.SetNestedField(u.Object, host, "spec", "host")
+		}
+		admitted := "True"
+		if !ready {
+			admitted = "False"
+		}
+		unstructured.SetNestedSlice(u.Object, []interface{}{
+			map[string]interface{}{
+				"host":       host,
+				"routerName": "default",
+				"conditions": []interface{}{
+					map[string]interface{}{"type": "Admitted", "status": admitted},
+				},
+			},
+		}, "status", "ingress")
+	case "Service":
+		unstructured.RemoveNestedField(u.Object, "status")
+		t, _, _ := unstructured.NestedString(u.Object, "spec", "type")
+		if t == "LoadBalancer" && ready {
+			unstructured.SetNestedSlice(u.Object, []interface{}{
+				map[string]interface{}{"ip": LoadBalancerIP},
+			}, "status", "loadBalancer", "ingress")
+		}
+	}
+
+	return u
+}
+
+// ready tells whether the simulated status of u is ready.
+func ready(u *unstructured.Unstructured) bool {
+	switch u.GetKind() {
+	case "Deployment", "StatefulSet", "Prometheus", "Alertmanager":
+		unavailable, _, _ := unstructured.NestedInt64(u.Object, "status", "unavailableReplicas")
+		return unavailable == 0
+	case "DaemonSet":
+		unavailable, _, _ := unstructured.NestedInt64(u.Object, "status", "numberUnavailable")
+		return unavailable == 0
+	case "Route":
+		ingress, _, _ := unstructured.NestedSlice(u.Object, "status", "ingress")
+		for _, i := range ingress {
+			conditions, _, _ := unstructured.NestedSlice(i.(map[string]interface{}), "conditions")
+			for _, c := range conditions {
+				cm := c.(map[string]interface{})
+				if cm["type"] == "Admitted" && cm["status"] == "True" {
+					return true
+				}
+			}
+		}
+		return false
+	case "Service":
+		t, _, _ := unstructured.NestedString(u.Object, "spec", "type")
+		ingress, _, _ := unstructured.NestedSlice(u.Object, "status", "loadBalancer", "ingress")
+		return t != "LoadBalancer" || len(ingress) > 0
+	}
+	return true
+}
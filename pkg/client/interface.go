@@ -0,0 +1,50 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Interface holds the operations the reconciliation tasks perform on the
+// API server. It is implemented by Client, and by the in-memory fake.Client
+// for testing tasks.
+type Interface interface {
+	// Namespace returns the namespace the monitoring stack is deployed
+	// in.
+	Namespace() string
+	// Apply reconciles obj according to policy.
+	Apply(ctx context.Context, obj runtime.Object, policy ApplyPolicy) error
+	// Get retrieves the live object of the kind, namespace and name of
+	// obj into obj.
+	Get(ctx context.Context, obj runtime.Object) error
+	// Restart restarts the pods of the workload obj and waits for the
+	// restarted pods to be ready.
+	Restart(ctx context.Context, obj runtime.Object) error
+	// WaitForReady waits for obj to be ready, such as a rollout to be
+	// done.
+	WaitForReady(ctx context.Context, obj runtime.Object) error
+	// WaitForRouteReady waits for the Route to be admitted, and returns
+	// its host.
+	WaitForRouteReady(ctx context.Context, r *routev1.Route) (string, error)
+	// WaitForPrometheusOperatorCRDsReady waits for the custom resources of
+	// the Prometheus Operator to be served.
+	WaitForPrometheusOperatorCRDsReady(ctx context.Context) error
+}
+
+var _ Interface = &Client{}
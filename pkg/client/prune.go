@@ -0,0 +1,157 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// PreventPruneAnnotation protects an object from being pruned when it is
+// set to "true".
+const PreventPruneAnnotation = "monitoring.openshift.io/prevent-prune"
+
+// AppliedSet records the objects applied with a context carrying it, so
+// that the managed objects that were not applied can be pruned.
+type AppliedSet struct {
+	mtx     sync.Mutex
+	objects map[appliedKey]bool
+}
+
+type appliedKey struct {
+	gk        schema.GroupKind
+	namespace string
+	name      string
+}
+
+type appliedSetKey struct{}
+
+// NewAppliedSet returns an empty AppliedSet.
+func NewAppliedSet() *AppliedSet {
+	return &AppliedSet{objects: map[appliedKey]bool{}}
+}
+
+// WithAppliedSet returns a copy of ctx carrying s, to which the objects
+// applied by the client given the returned context are added.
+func WithAppliedSet(ctx context.Context, s *AppliedSet) context.Context {
+	return context.WithValue(ctx, appliedSetKey{}, s)
+}
+
+func (s *AppliedSet) add(gvk schema.GroupVersionKind, namespace, name string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.objects[appliedKey{gvk.GroupKind(), namespace, name}] = true
+}
+
+// Contains returns true if the object of the given kind, namespace and name
+// was applied. Versions of a kind are not told apart.
+func (s *AppliedSet) Contains(gvk schema.GroupVersionKind, namespace, name string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.objects[appliedKey{gvk.GroupKind(), namespace, name}]
+}
+
+// recordApplied adds the object to the AppliedSet of ctx, if any.
+func recordApplied(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) {
+	if s, ok := ctx.Value(appliedSetKey{}).(*AppliedSet); ok {
+		s.add(gvk, namespace, name)
+	}
+}
+
+// Prune deletes the objects of the given kinds matching labelSelector in
+// all namespaces that are not in applied, except for the objects carrying
+// the PreventPruneAnnotation. Kinds not served by the API server are
+// skipped. With dryRun, the objects are not deleted. The objects that were,
+// or with dryRun would have been, deleted are returned.
+func (c *Client) Prune(ctx context.Context, applied *AppliedSet, kinds []schema.GroupVersionKind, labelSelector string, dryRun bool) ([]*unstructured.Unstructured, error) {
+	pruned := []*unstructured.Unstructured{}
+	errs := []error{}
+
+	for _, gvk := range kinds {
+		if err := ctx.Err(); err != nil {
+			return pruned, err
+		}
+
+		l, err := c.ListUnstructured(gvk, labelSelector)
+		if meta.IsNoMatchError(err) {
+			glog.V(4).Infof("not pruning %s objects: %v", gvk.Kind, err)
+			continue
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "listing %s objects failed", gvk.Kind))
+			continue
+		}
+
+		for i := range l.Items {
+			obj := &l.Items[i]
+			if applied.Contains(gvk, obj.GetNamespace(), obj.GetName()) || obj.GetDeletionTimestamp() != nil {
+				continue
+			}
+			if obj.GetAnnotations()[PreventPruneAnnotation] == "true" {
+				glog.V(4).Infof("not pruning %s %s, as it is protected", gvk.Kind, nameOf(obj))
+				continue
+			}
+
+			if dryRun {
+				glog.Infof("%s %s is not desired anymore and would be pruned (dry run)", gvk.Kind, nameOf(obj))
+				pruned = append(pruned, obj)
+				continue
+			}
+
+			if err := c.deleteUnstructured(gvk, obj); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			glog.Infof("pruned %s %s, which is not desired anymore", gvk.Kind, nameOf(obj))
+			pruned = append(pruned, obj)
+		}
+	}
+
+	return pruned, utilerrors.NewAggregate(errs)
+}
+
+func (c *Client) deleteUnstructured(gvk schema.GroupVersionKind, obj *unstructured.Unstructured) error {
+	ri, err := c.resourceInterface(gvk, obj.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	uid := obj.GetUID()
+	err = ri.Delete(obj.GetName(), &metav1.DeleteOptions{
+		PropagationPolicy: &propagation,
+		// Do not delete an object that was replaced in the meantime.
+		Preconditions: &metav1.Preconditions{UID: &uid},
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		c.eventf(obj, v1.EventTypeWarning, reasonDeleteFailed, "Pruning %s %s failed: %v", gvk.Kind, nameOf(obj), err)
+		return errors.Wrapf(err, "deleting %s %s failed", gvk.Kind, nameOf(obj))
+	}
+	c.eventf(obj, v1.EventTypeNormal, reasonDeleted, "Pruned %s %s, which is not desired anymore", gvk.Kind, nameOf(obj))
+	return nil
+}
@@ -0,0 +1,61 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	appsv1 "k8s.io/api/apps/v1beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestAppliedSet(t *testing.T) {
+	s := NewAppliedSet()
+	ctx := WithAppliedSet(context.Background(), s)
+
+	route := &routev1.Route{
+		// The assets use the legacy API version of Routes.
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Route"},
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "openshift-monitoring"},
+	}
+	gvk, err := GroupVersionKindFor(route)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gvk.Group != routev1.GroupName {
+		t.Fatalf("expected the Route to be in group %s, got %v", routev1.GroupName, gvk)
+	}
+	recordApplied(ctx, gvk, route.Namespace, route.Name)
+	recordApplied(context.Background(), appsv1.SchemeGroupVersion.WithKind("Deployment"), "openshift-monitoring", "grafana")
+
+	for _, tc := range []struct {
+		gvk       schema.GroupVersionKind
+		namespace string
+		name      string
+		applied   bool
+	}{
+		{routev1.SchemeGroupVersion.WithKind("Route"), "openshift-monitoring", "grafana", true},
+		{schema.GroupVersionKind{Group: routev1.GroupName, Version: "v2", Kind: "Route"}, "openshift-monitoring", "grafana", true},
+		{routev1.SchemeGroupVersion.WithKind("Route"), "default", "grafana", false},
+		{appsv1.SchemeGroupVersion.WithKind("Deployment"), "openshift-monitoring", "grafana", false},
+	} {
+		if applied := s.Contains(tc.gvk, tc.namespace, tc.name); applied != tc.applied {
+			t.Errorf("%v %s/%s: expected applied to be %v, got %v", tc.gvk, tc.namespace, tc.name, tc.applied, applied)
+		}
+	}
+}
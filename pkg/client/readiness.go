@@ -0,0 +1,258 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreos/prometheus-operator/pkg/alertmanager"
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	prometheusoperator "github.com/coreos/prometheus-operator/pkg/prometheus"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+const rolloutTimeout = 5 * time.Minute
+
+// readinessChecker tells whether a live object of a kind is ready.
+type readinessChecker struct {
+	// interval is the default interval at which the readiness of the
+	// object is checked if no change of it or its pods is observed.
+	interval time.Duration
+	// reason is the reason of the event recorded if the object does not
+	// become ready.
+	reason string
+	ready  func(c *Client, obj *unstructured.Unstructured) (bool, error)
+	// pods returns the selector of the pods of the object, whose changes
+	// are watched as well and whose state is diagnosed if the object does
+	// not become ready. It is nil for kinds without pods.
+	pods func(obj *unstructured.Unstructured) (labels.Selector, error)
+}
+
+// readinessCheckers are the readiness checkers of the kinds that take time
+// to become ready. Objects of other kinds are ready once applied.
+var readinessCheckers = map[schema.GroupKind]readinessChecker{
+	{Group: "apps", Kind: "Deployment"}:                               {time.Second, reasonRolloutFailed, deploymentReady, workloadPods},
+	{Group: "extensions", Kind: "Deployment"}:                         {time.Second, reasonRolloutFailed, deploymentReady, workloadPods},
+	{Group: "apps", Kind: "DaemonSet"}:                                {time.Second, reasonRolloutFailed, daemonSetReady, workloadPods},
+	{Group: "extensions", Kind: "DaemonSet"}:                          {time.Second, reasonRolloutFailed, daemonSetReady, workloadPods},
+	{Group: "apps", Kind: "StatefulSet"}:                              {time.Second, reasonRolloutFailed, statefulSetReady, workloadPods},
+	{Group: monv1.Group, Kind: monv1.PrometheusesKind}:                {10 * time.Second, reasonRolloutFailed, prometheusReady, prometheusPods},
+	{Group: monv1.Group, Kind: monv1.AlertmanagersKind}:               {10 * time.Second, reasonRolloutFailed, alertmanagerReady, alertmanagerPods},
+	{Group: "route.openshift.io", Kind: "Route"}:                      {time.Second, reasonRouteNotReady, routeAdmitted, nil},
+	{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}: {5 * time.Second, reasonRolloutFailed, crdEstablished, nil},
+	{Group: "", Kind: "Service"}:                                      {5 * time.Second, reasonLoadBalancerNotReady, loadBalancerProvisioned, nil},
+}
+
+// WaitForReady waits for the object to be ready, as told by the readiness
+// checker of its kind. Objects of kinds without readiness checker are
+// considered ready. Readiness is checked whenever the object or its pods
+// change. If the object does not become ready in time, a RolloutError
+// telling why is returned.
+func (c *Client) WaitForReady(ctx context.Context, obj runtime.Object) error {
+	gvk, err := GroupVersionKindFor(obj)
+	if err != nil {
+		return err
+	}
+	rc, ok := readinessCheckers[gvk.GroupKind()]
+	if !ok {
+		return nil
+	}
+
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	ri, err := c.resourceInterface(gvk, a.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	var selector labels.Selector
+	if rc.pods != nil {
+		m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+		if err != nil {
+			return errors.Wrapf(err, "converting %s object failed", gvk.Kind)
+		}
+		if selector, err = rc.pods(&unstructured.Unstructured{Object: m}); err != nil {
+			return errors.Wrapf(err, "retrieving pod selector of %s failed", gvk.Kind)
+		}
+	}
+
+	var live *unstructured.Unstructured
+	watches := c.readinessWatches(ri, a.GetNamespace(), a.GetName(), selector)
+	err = watchUntil(ctx, rc.interval, rolloutTimeout, watches, func() (bool, error) {
+		var err error
+		live, err = ri.Get(a.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return rc.ready(c, live)
+	})
+	if err == wait.ErrWaitTimeout {
+		err = c.diagnose(live, selector, err)
+	}
+	c.recordWaitFailed(obj, rc.reason, err)
+	return err
+}
+
+// readinessWatches returns the watches on the object of the given name and,
+// unless selector is nil, on its pods.
+func (c *Client) readinessWatches(ri dynamic.ResourceInterface, namespace, name string, selector labels.Selector) []watchFunc {
+	watches := []watchFunc{func() (watch.Interface, error) {
+		return ri.Watch(metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", name).String()})
+	}}
+	if selector != nil {
+		watches = append(watches, func() (watch.Interface, error) {
+			return c.kclient.CoreV1().Pods(namespace).Watch(metav1.ListOptions{LabelSelector: selector.String()})
+		})
+	}
+	return watches
+}
+
+// workloadPods returns the selector of the pods of a Deployment, DaemonSet
+// or StatefulSet.
+func workloadPods(obj *unstructured.Unstructured) (labels.Selector, error) {
+	m, found, err := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if err != nil || !found {
+		// Old API versions default the selector to the labels of the
+		// pod template.
+		podLabels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+		return labels.SelectorFromSet(podLabels), nil
+	}
+
+	ls := &metav1.LabelSelector{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(m, ls); err != nil {
+		return nil, errors.Wrap(err, "converting label selector failed")
+	}
+	return metav1.LabelSelectorAsSelector(ls)
+}
+
+func prometheusPods(obj *unstructured.Unstructured) (labels.Selector, error) {
+	return labels.Parse(prometheusoperator.ListOptions(obj.GetName()).LabelSelector)
+}
+
+func alertmanagerPods(obj *unstructured.Unstructured) (labels.Selector, error) {
+	return labels.Parse(alertmanager.ListOptions(obj.GetName()).LabelSelector)
+}
+
+func nestedInt64(obj *unstructured.Unstructured, fields ...string) int64 {
+	v, _, _ := unstructured.NestedInt64(obj.Object, fields...)
+	return v
+}
+
+func deploymentReady(_ *Client, d *unstructured.Unstructured) (bool, error) {
+	return d.GetGeneration() <= nestedInt64(d, "status", "observedGeneration") &&
+		nestedInt64(d, "status", "updatedReplicas") == nestedInt64(d, "status", "replicas") &&
+		nestedInt64(d, "status", "unavailableReplicas") == 0, nil
+}
+
+func daemonSetReady(_ *Client, ds *unstructured.Unstructured) (bool, error) {
+	return ds.GetGeneration() <= nestedInt64(ds, "status", "observedGeneration") &&
+		nestedInt64(ds, "status", "updatedNumberScheduled") == nestedInt64(ds, "status", "desiredNumberScheduled") &&
+		nestedInt64(ds, "status", "numberUnavailable") == 0, nil
+}
+
+func statefulSetReady(_ *Client, ss *unstructured.Unstructured) (bool, error) {
+	replicas, found, _ := unstructured.NestedInt64(ss.Object, "spec", "replicas")
+	if !found {
+		replicas = 1
+	}
+	return ss.GetGeneration() <= nestedInt64(ss, "status", "observedGeneration") &&
+		nestedInt64(ss, "status", "updatedReplicas") == replicas &&
+		nestedInt64(ss, "status", "readyReplicas") == replicas, nil
+}
+
+func prometheusReady(c *Client, obj *unstructured.Unstructured) (bool, error) {
+	p := &monv1.Prometheus{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, p); err != nil {
+		return false, errors.Wrap(err, "converting Prometheus object failed")
+	}
+	status, _, err := prometheusoperator.PrometheusStatus(c.kclient.(*kubernetes.Clientset), p)
+	if err != nil {
+		return false, errors.Wrap(err, "retrieving Prometheus status failed")
+	}
+
+	expectedReplicas := int32(1)
+	if p.Spec.Replicas != nil {
+		expectedReplicas = *p.Spec.Replicas
+	}
+	return status.UpdatedReplicas == expectedReplicas && status.AvailableReplicas >= expectedReplicas, nil
+}
+
+func alertmanagerReady(c *Client, obj *unstructured.Unstructured) (bool, error) {
+	a := &monv1.Alertmanager{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, a); err != nil {
+		return false, errors.Wrap(err, "converting Alertmanager object failed")
+	}
+	status, _, err := alertmanager.AlertmanagerStatus(c.kclient.(*kubernetes.Clientset), a)
+	if err != nil {
+		return false, errors.Wrap(err, "retrieving Alertmanager status failed")
+	}
+
+	expectedReplicas := int32(1)
+	if a.Spec.Replicas != nil {
+		expectedReplicas = *a.Spec.Replicas
+	}
+	return status.UpdatedReplicas == expectedReplicas && status.AvailableReplicas >= expectedReplicas, nil
+}
+
+func routeAdmitted(_ *Client, r *unstructured.Unstructured) (bool, error) {
+	ingress, _, _ := unstructured.NestedSlice(r.Object, "status", "ingress")
+	if len(ingress) == 0 {
+		return false, nil
+	}
+	first, _ := ingress[0].(map[string]interface{})
+	return hasCondition(first, "Admitted"), nil
+}
+
+// loadBalancerProvisioned returns true unless the Service is of type
+// LoadBalancer and its load balancer was not provisioned yet.
+func loadBalancerProvisioned(_ *Client, s *unstructured.Unstructured) (bool, error) {
+	if t, _, _ := unstructured.NestedString(s.Object, "spec", "type"); t != "LoadBalancer" {
+		return true, nil
+	}
+	ingress, _, _ := unstructured.NestedSlice(s.Object, "status", "loadBalancer", "ingress")
+	return len(ingress) > 0, nil
+}
+
+func crdEstablished(_ *Client, crd *unstructured.Unstructured) (bool, error) {
+	status, _, _ := unstructured.NestedMap(crd.Object, "status")
+	return hasCondition(status, "Established"), nil
+}
+
+// hasCondition returns true if the conditions of obj contain a condition
+// of the given type with status True.
+func hasCondition(obj map[string]interface{}, conditionType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(obj, "conditions")
+	for _, c := range conditions {
+		cm, ok := c.(map[string]interface{})
+		if ok && cm["type"] == conditionType && cm["status"] == "True" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,127 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func TestReadinessCheckers(t *testing.T) {
+	cases := []struct {
+		name  string
+		ready func(*Client, *unstructured.Unstructured) (bool, error)
+		obj   string
+		want  bool
+	}{
+		{
+			name:  "deployment rolled out",
+			ready: deploymentReady,
+			obj:   `{"metadata":{"generation":2},"status":{"observedGeneration":2,"replicas":2,"updatedReplicas":2}}`,
+			want:  true,
+		}, {
+			name:  "deployment change not observed",
+			ready: deploymentReady,
+			obj:   `{"metadata":{"generation":3},"status":{"observedGeneration":2,"replicas":2,"updatedReplicas":2}}`,
+		}, {
+			name:  "deployment with unavailable replicas",
+			ready: deploymentReady,
+			obj:   `{"metadata":{"generation":2},"status":{"observedGeneration":2,"replicas":2,"updatedReplicas":2,"unavailableReplicas":1}}`,
+		}, {
+			name:  "daemonset rolled out",
+			ready: daemonSetReady,
+			obj:   `{"metadata":{"generation":1},"status":{"observedGeneration":1,"desiredNumberScheduled":3,"updatedNumberScheduled":3}}`,
+			want:  true,
+		}, {
+			name:  "daemonset rolling out",
+			ready: daemonSetReady,
+			obj:   `{"metadata":{"generation":1},"status":{"observedGeneration":1,"desiredNumberScheduled":3,"updatedNumberScheduled":2}}`,
+		}, {
+			name:  "route admitted",
+			ready: routeAdmitted,
+			obj:   `{"status":{"ingress":[{"conditions":[{"type":"Admitted","status":"True"}]}]}}`,
+			want:  true,
+		}, {
+			name:  "route not admitted yet",
+			ready: routeAdmitted,
+			obj:   `{"status":{"ingress":[{"conditions":[{"type":"Admitted","status":"False"}]}]}}`,
+		}, {
+			name:  "route without ingress",
+			ready: routeAdmitted,
+			obj:   `{"status":{}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON([]byte(`{"apiVersion":"v1","kind":"Test",` + tc.obj[1:])); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got, err := tc.ready(nil, obj)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: expected ready to be %t, got %t", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestPodSelectors(t *testing.T) {
+	cases := []struct {
+		name     string
+		pods     func(*unstructured.Unstructured) (labels.Selector, error)
+		obj      string
+		expected string
+	}{
+		{
+			name:     "deployment selector",
+			pods:     workloadPods,
+			obj:      `{"spec":{"selector":{"matchLabels":{"app":"grafana"}},"template":{"metadata":{"labels":{"app":"grafana","version":"1"}}}}}`,
+			expected: "app=grafana",
+		}, {
+			name:     "defaulted selector",
+			pods:     workloadPods,
+			obj:      `{"spec":{"template":{"metadata":{"labels":{"app":"node-exporter"}}}}}`,
+			expected: "app=node-exporter",
+		}, {
+			name:     "prometheus",
+			pods:     prometheusPods,
+			obj:      `{"metadata":{"name":"k8s"}}`,
+			expected: "app=prometheus,prometheus=k8s",
+		}, {
+			name:     "alertmanager",
+			pods:     alertmanagerPods,
+			obj:      `{"metadata":{"name":"main"}}`,
+			expected: "alertmanager=main,app=alertmanager",
+		},
+	}
+
+	for _, tc := range cases {
+		obj := &unstructured.Unstructured{}
+		if err := obj.UnmarshalJSON([]byte(`{"apiVersion":"v1","kind":"Test",` + tc.obj[1:])); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		s, err := tc.pods(obj)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if s.String() != tc.expected {
+			t.Errorf("%s: expected selector %q, got %q", tc.name, tc.expected, s)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// RestartedAtAnnotation is set on the pod template of the workloads
+// restarted by Restart, to the time of the restart.
+const RestartedAtAnnotation = "monitoring.openshift.io/restarted-at"
+
+// Restart restarts the pods of a Deployment, DaemonSet, StatefulSet,
+// Prometheus or Alertmanager by setting RestartedAtAnnotation on its pod
+// template, and waits until all of its pods were replaced and are ready.
+// The annotation is not part of the applied configuration of the object, so
+// that applying it again keeps the annotation and does not restart the pods
+// again.
+func (c *Client) Restart(ctx context.Context, obj runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, err := GroupVersionKindFor(obj)
+	if err != nil {
+		return err
+	}
+	rc, ok := readinessCheckers[gvk.GroupKind()]
+	if !ok || rc.pods == nil {
+		return errors.Errorf("%s objects have no pods to restart", gvk.Kind)
+	}
+
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	ri, err := c.resourceInterface(gvk, a.GetNamespace())
+	if err != nil {
+		return err
+	}
+
+	// The Prometheus Operator copies the pod metadata of Prometheus and
+	// Alertmanager objects to the pod template of their StatefulSets.
+	path := []string{"spec", "template", "metadata", "annotations", RestartedAtAnnotation}
+	if gvk.Group == monv1.Group {
+		path = []string{"spec", "podMetadata", "annotations", RestartedAtAnnotation}
+	}
+	restartedAt := time.Now().UTC().Format(time.RFC3339Nano)
+	patch := map[string]interface{}{}
+	if err := unstructured.SetNestedField(patch, restartedAt, path...); err != nil {
+		return err
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return errors.Wrap(err, "encoding patch failed")
+	}
+
+	live, err := ri.Patch(a.GetName(), types.MergePatchType, data)
+	if err != nil {
+		return errors.Wrapf(err, "restarting %s object failed", gvk.Kind)
+	}
+	c.eventf(live, v1.EventTypeNormal, reasonRestarted, "Restarted the pods of %s %s", gvk.Kind, nameOf(live))
+
+	selector, err := rc.pods(live)
+	if err != nil {
+		return errors.Wrapf(err, "retrieving pod selector of %s failed", gvk.Kind)
+	}
+
+	watches := c.readinessWatches(ri, a.GetNamespace(), a.GetName(), selector)
+	err = watchUntil(ctx, rc.interval, rolloutTimeout, watches, func() (bool, error) {
+		var err error
+		live, err = ri.Get(a.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		if ready, err := rc.ready(c, live); err != nil || !ready {
+			return false, err
+		}
+
+		pods, err := c.kclient.CoreV1().Pods(a.GetNamespace()).List(metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return false, err
+		}
+		for _, p := range pods.Items {
+			if p.Annotations[RestartedAtAnnotation] != restartedAt || p.DeletionTimestamp != nil || !podReady(&p) {
+				return false, nil
+			}
+		}
+		return len(pods.Items) > 0, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		err = c.diagnose(live, selector, err)
+	}
+	c.recordWaitFailed(obj, rc.reason, err)
+	return err
+}
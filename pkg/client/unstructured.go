@@ -0,0 +1,131 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// resourceFor returns the client of the resource of the given kind, and
+// whether the resource is namespaced. The resources are discovered from the
+// API server and cached. A meta.NoKindMatchError is returned if the API
+// server does not serve the kind.
+func (c *Client) resourceFor(gvk schema.GroupVersionKind) (dynamic.NamespaceableResourceInterface, bool, error) {
+	c.resourcesMtx.Lock()
+	defer c.resourcesMtx.Unlock()
+
+	if c.resources == nil {
+		c.resources = map[schema.GroupVersionKind]metav1.APIResource{}
+	}
+
+	r, ok := c.resources[gvk]
+	if !ok {
+		l, err := c.kclient.Discovery().ServerResourcesForGroupVersion(gvk.GroupVersion().String())
+		if apierrors.IsNotFound(err) {
+			return nil, false, &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+		}
+		if err != nil {
+			return nil, false, errors.Wrapf(err, "discovering resources of %s failed", gvk.GroupVersion())
+		}
+		for _, ar := range l.APIResources {
+			// Skip subresources such as deployments/scale.
+			if strings.Contains(ar.Name, "/") {
+				continue
+			}
+			c.resources[gvk.GroupVersion().WithKind(ar.Kind)] = ar
+		}
+		if r, ok = c.resources[gvk]; !ok {
+			return nil, false, &meta.NoKindMatchError{GroupKind: gvk.GroupKind(), SearchedVersions: []string{gvk.Version}}
+		}
+	}
+
+	gvr := gvk.GroupVersion().WithResource(r.Name)
+	return c.dclient.Resource(gvr), r.Namespaced, nil
+}
+
+// resourceInterface returns the client of the objects of the given kind in
+// namespace. The namespace is ignored for cluster-scoped kinds.
+func (c *Client) resourceInterface(gvk schema.GroupVersionKind, namespace string) (dynamic.ResourceInterface, error) {
+	rc, namespaced, err := c.resourceFor(gvk)
+	if err != nil {
+		return nil, err
+	}
+	if !namespaced {
+		return rc, nil
+	}
+	return rc.Namespace(namespace), nil
+}
+
+// Serves returns true if the API server serves the given kind.
+func (c *Client) Serves(gvk schema.GroupVersionKind) (bool, error) {
+	_, _, err := c.resourceFor(gvk)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// GetUnstructured returns the live object of the given kind, namespace and
+// name.
+func (c *Client) GetUnstructured(gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error) {
+	ri, err := c.resourceInterface(gvk, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ri.Get(name, metav1.GetOptions{})
+}
+
+// Get retrieves the live object of the kind, namespace and name of obj into
+// obj.
+func (c *Client) Get(ctx context.Context, obj runtime.Object) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	gvk, err := GroupVersionKindFor(obj)
+	if err != nil {
+		return err
+	}
+	a, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+
+	live, err := c.GetUnstructured(gvk, a.GetNamespace(), a.GetName())
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(runtime.DefaultUnstructuredConverter.FromUnstructured(live.Object, obj), "converting %s object failed", gvk.Kind)
+}
+
+// ListUnstructured lists the live objects of the given kind matching the
+// label selector in all namespaces.
+func (c *Client) ListUnstructured(gvk schema.GroupVersionKind, labelSelector string) (*unstructured.UnstructuredList, error) {
+	rc, _, err := c.resourceFor(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return rc.List(metav1.ListOptions{LabelSelector: labelSelector})
+}
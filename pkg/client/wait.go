@@ -0,0 +1,153 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// WaitPolicy overrides how long the client waits for objects, such as
+// rollouts, to be ready, and how often it checks their readiness when no
+// change is observed. Zero values keep the defaults of each wait.
+type WaitPolicy struct {
+	Timeout  time.Duration
+	Interval time.Duration
+}
+
+type waitPolicyKey struct{}
+
+// WithWaitPolicy returns a copy of ctx carrying p, which the waits of the
+// client given the returned context honor.
+func WithWaitPolicy(ctx context.Context, p WaitPolicy) context.Context {
+	return context.WithValue(ctx, waitPolicyKey{}, p)
+}
+
+// withWaitPolicy returns interval and timeout, overridden by the WaitPolicy
+// of ctx, if any.
+func withWaitPolicy(ctx context.Context, interval, timeout time.Duration) (time.Duration, time.Duration) {
+	if p, ok := ctx.Value(waitPolicyKey{}).(WaitPolicy); ok {
+		if p.Interval > 0 {
+			interval = p.Interval
+		}
+		if p.Timeout > 0 {
+			timeout = p.Timeout
+		}
+	}
+	return interval, timeout
+}
+
+// poll is like wait.Poll, but also stops polling once ctx is done, in which
+// case the error of ctx is returned unless the timeout expired first. The
+// interval and timeout are overridden by the WaitPolicy of ctx, if any.
+func poll(ctx context.Context, interval, timeout time.Duration, condition wait.ConditionFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	interval, timeout = withWaitPolicy(ctx, interval, timeout)
+	pctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := wait.PollUntil(interval, condition, pctx.Done())
+	if err == wait.ErrWaitTimeout && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// watchFunc starts a watch on objects whose changes may make a condition
+// true.
+type watchFunc func() (watch.Interface, error)
+
+// watchUntil waits until condition returns true or an error. The condition
+// is checked right away, whenever one of the watches started by watches
+// reports an event, and every interval, in case events are missed while a
+// watch is restarted. Like poll, it stops once ctx is done, and the interval
+// and timeout are overridden by the WaitPolicy of ctx, if any.
+func watchUntil(ctx context.Context, interval, timeout time.Duration, watches []watchFunc, condition wait.ConditionFunc) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	interval, timeout = withWaitPolicy(ctx, interval, timeout)
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	for _, w := range watches {
+		go notifyChanges(wctx, w, interval, changed)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ok, err := condition()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+
+		select {
+		case <-wctx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return wait.ErrWaitTimeout
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifyChanges signals changed on every event of the watch started by w
+// until ctx is done. The watch is restarted after retry when it fails to
+// start or is closed by the API server.
+func notifyChanges(ctx context.Context, w watchFunc, retry time.Duration, changed chan<- struct{}) {
+	for {
+		if wi, err := w(); err == nil {
+			forwardEvents(ctx, wi, changed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+func forwardEvents(ctx context.Context, wi watch.Interface, changed chan<- struct{}) {
+	defer wi.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-wi.ResultChan():
+			if !ok {
+				return
+			}
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestPollHonorsWaitPolicy(t *testing.T) {
+	ctx := WithWaitPolicy(context.Background(), WaitPolicy{
+		Timeout:  50 * time.Millisecond,
+		Interval: 10 * time.Millisecond,
+	})
+
+	calls := 0
+	start := time.Now()
+	err := poll(ctx, time.Minute, time.Hour, func() (bool, error) {
+		calls++
+		return false, nil
+	})
+	if err != wait.ErrWaitTimeout {
+		t.Fatalf("expected the wait to time out, got %v", err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("expected the timeout of the policy to be used, waited %v", d)
+	}
+	if calls < 2 {
+		t.Fatalf("expected the interval of the policy to be used, polled %d times", calls)
+	}
+}
+
+func TestPollCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	err := poll(ctx, 10*time.Millisecond, time.Hour, func() (bool, error) {
+		return false, nil
+	})
+	if err != context.Canceled {
+		t.Fatalf("expected the cancellation to be returned, got %v", err)
+	}
+}
+
+func TestWatchUntilChecksOnEvents(t *testing.T) {
+	w := watch.NewFake()
+	watches := []watchFunc{func() (watch.Interface, error) { return w, nil }}
+
+	ready := make(chan bool, 1)
+	ready <- false
+	go func() {
+		ready <- true
+		w.Modify(&v1.Pod{})
+	}()
+
+	start := time.Now()
+	err := watchUntil(context.Background(), time.Hour, time.Hour, watches, func() (bool, error) {
+		return <-ready, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("expected the condition to be checked on the event, waited %v", d)
+	}
+}
+
+func TestWatchUntilTimeout(t *testing.T) {
+	ctx := WithWaitPolicy(context.Background(), WaitPolicy{Timeout: 50 * time.Millisecond})
+	failing := func() (watch.Interface, error) { return nil, errors.New("watch failed") }
+
+	calls := 0
+	err := watchUntil(ctx, 10*time.Millisecond, time.Hour, []watchFunc{failing}, func() (bool, error) {
+		calls++
+		return false, nil
+	})
+	if err != wait.ErrWaitTimeout {
+		t.Fatalf("expected the wait to time out, got %v", err)
+	}
+	if calls < 2 {
+		t.Fatalf("expected the condition to be checked every interval without watch, checked %d times", calls)
+	}
+}
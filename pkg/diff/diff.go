@@ -0,0 +1,338 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diff compares the objects rendered for a configuration to their
+// live counterparts on a cluster.
+//
+// The comparison is semantic: only the fields set by the operator are
+// compared, so that fields defaulted or populated by the API server, such as
+// the resourceVersion, the clusterIP of Services or the status, are ignored.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/render"
+)
+
+type Action string
+
+const (
+	// ActionCreate means the object does not exist and would be created.
+	ActionCreate Action = "create"
+	// ActionUpdate means the live object differs and would be updated.
+	ActionUpdate Action = "update"
+	// ActionNone means the live object does not differ.
+	ActionNone Action = "none"
+	// ActionOrphan means the live object is managed by the operator but
+	// not rendered anymore, so it would be left behind.
+	ActionOrphan Action = "orphan"
+)
+
+// ignoredPaths are populated by the API server and never compared.
+var ignoredPaths = map[string]bool{
+	"metadata.creationTimestamp": true,
+	"metadata.generation":        true,
+	"metadata.resourceVersion":   true,
+	"metadata.selfLink":          true,
+	"metadata.uid":               true,
+	"spec.clusterIP":             true,
+	"status":                     true,
+}
+
+// ignoredAnnotations are set by the operator when applying objects, and
+// are not reported as removed.
+var ignoredAnnotations = map[string]bool{
+	client.LastAppliedAnnotation: true,
+}
+
+// Change is the difference of a single field. Live and Desired are empty if
+// the field is not set on the live or the desired object.
+type Change struct {
+	Path    string
+	Live    string
+	Desired string
+}
+
+// Result is the outcome of comparing one object.
+type Result struct {
+	Component string
+	Kind      string
+	Namespace string
+	Name      string
+	Action    Action
+	Changes   []Change
+}
+
+// Getter retrieves live objects. It is implemented by client.Client.
+type Getter interface {
+	GetUnstructured(gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error)
+	ListUnstructured(gvk schema.GroupVersionKind, labelSelector string) (*unstructured.UnstructuredList, error)
+}
+
+// Diff compares the rendered components to the live objects. Objects that
+// are only created if they do not exist are not compared if they exist.
+// Live objects carrying the managed-by label of the operator, of any of the
+// managed kinds, that are not rendered are reported as orphans, including
+// the ones of kinds that are not rendered at all anymore. Kinds the cluster
+// does not serve are skipped.
+func Diff(components []*render.Component, g Getter) ([]*Result, error) {
+	results := []*Result{}
+	rendered := map[string]bool{}
+
+	for _, c := range components {
+		for i, obj := range c.Objects {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			a, err := meta.Accessor(obj)
+			if err != nil {
+				return nil, err
+			}
+			rendered[objectKey(gvk.Kind, a.GetNamespace(), a.GetName())] = true
+
+			r := &Result{
+				Component: c.Name,
+				Kind:      gvk.Kind,
+				Namespace: a.GetNamespace(),
+				Name:      a.GetName(),
+			}
+			results = append(results, r)
+
+			live, err := g.GetUnstructured(gvk, a.GetNamespace(), a.GetName())
+			if apierrors.IsNotFound(err) {
+				r.Action = ActionCreate
+				continue
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "retrieving %s %s failed", gvk.Kind, a.GetName())
+			}
+			if c.CreateOnly(i) {
+				r.Action = ActionNone
+				continue
+			}
+
+			r.Changes, err = Object(obj, live)
+			if err != nil {
+				return nil, errors.Wrapf(err, "comparing %s %s failed", gvk.Kind, a.GetName())
+			}
+			r.Action = ActionNone
+			if len(r.Changes) > 0 {
+				r.Action = ActionUpdate
+			}
+		}
+	}
+
+	selector := manifests.ManagedByLabel + "=" + manifests.ManagedByValue
+	for _, gvk := range manifests.ManagedKinds {
+		l, err := g.ListUnstructured(gvk, selector)
+		if meta.IsNoMatchError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "listing %s objects failed", gvk.Kind)
+		}
+		for _, live := range l.Items {
+			if rendered[objectKey(gvk.Kind, live.GetNamespace(), live.GetName())] {
+				continue
+			}
+			results = append(results, &Result{
+				Component: live.GetLabels()[manifests.ComponentLabel],
+				Kind:      gvk.Kind,
+				Namespace: live.GetNamespace(),
+				Name:      live.GetName(),
+				Action:    ActionOrphan,
+			})
+		}
+	}
+
+	return results, nil
+}
+
+func objectKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// Object returns the differences between the fields set on desired and the
+// same fields of live. Labels and annotations missing from desired are
+// reported too, as updating the object removes them. The values of Secrets
+// are not revealed.
+func Object(desired runtime.Object, live *unstructured.Unstructured) ([]Change, error) {
+	d, err := runtime.DefaultUnstructuredConverter.ToUnstructured(desired)
+	if err != nil {
+		return nil, errors.Wrap(err, "converting desired object failed")
+	}
+
+	c := &comparer{secret: desired.GetObjectKind().GroupVersionKind().Kind == "Secret"}
+	c.compare("", d, live.Object)
+	return c.changes, nil
+}
+
+type comparer struct {
+	secret  bool
+	changes []Change
+}
+
+func (c *comparer) compare(path string, desired, live interface{}) {
+	if ignoredPaths[path] || isEmpty(desired) {
+		return
+	}
+
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			c.add(path, live, desired)
+			return
+		}
+		for _, k := range sortedKeys(d) {
+			c.compare(join(path, k), d[k], l[k])
+		}
+		if path == "metadata.labels" || path == "metadata.annotations" {
+			for _, k := range sortedKeys(l) {
+				if _, ok := d[k]; !ok && !ignoredAnnotations[k] {
+					c.add(join(path, k), l[k], nil)
+				}
+			}
+		}
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok {
+			c.add(path, live, desired)
+			return
+		}
+		for i := range d {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			if i >= len(l) {
+				c.add(p, nil, d[i])
+				continue
+			}
+			c.compare(p, d[i], l[i])
+		}
+		for i := len(d); i < len(l); i++ {
+			c.add(fmt.Sprintf("%s[%d]", path, i), l[i], nil)
+		}
+	default:
+		if !equalScalars(desired, live) {
+			c.add(path, live, desired)
+		}
+	}
+}
+
+func (c *comparer) add(path string, live, desired interface{}) {
+	ch := Change{Path: path}
+	hide := c.secret && (strings.HasPrefix(path, "data.") || strings.HasPrefix(path, "stringData."))
+	if live != nil {
+		ch.Live = format(live, hide)
+	}
+	if desired != nil {
+		ch.Desired = format(desired, hide)
+	}
+	c.changes = append(c.changes, ch)
+}
+
+func format(v interface{}, hide bool) string {
+	if hide {
+		return "(hidden)"
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
+
+// equalScalars compares JSON scalars, treating numbers of different types
+// and equal resource quantities, such as 0.5 and 500m, as equal.
+func equalScalars(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+
+	sa, ok := a.(string)
+	if !ok {
+		return false
+	}
+	sb, ok := b.(string)
+	if !ok {
+		return false
+	}
+	qa, err := resource.ParseQuantity(sa)
+	if err != nil {
+		return false
+	}
+	qb, err := resource.ParseQuantity(sb)
+	return err == nil && qa.Cmp(qb) == 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// isEmpty returns true for values that are not set on the desired object,
+// which the API server drops.
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,247 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/render"
+)
+
+func toUnstructured(t *testing.T, obj runtime.Object) *unstructured.Unstructured {
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &unstructured.Unstructured{Object: m}
+}
+
+func service(port int32) *v1.Service {
+	return &v1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "grafana",
+			Namespace: "openshift-monitoring",
+			Labels:    map[string]string{manifests.ManagedByLabel: manifests.ManagedByValue},
+		},
+		Spec: v1.ServiceSpec{
+			Ports: []v1.ServicePort{{Name: "https", Port: port}},
+		},
+	}
+}
+
+func TestObjectIgnoresServerPopulatedFields(t *testing.T) {
+	live := service(3000)
+	live.ResourceVersion = "42"
+	live.UID = "uid"
+	live.Spec.ClusterIP = "10.0.0.1"
+	live.Spec.SessionAffinity = v1.ServiceAffinityNone
+	live.Status.LoadBalancer.Ingress = []v1.LoadBalancerIngress{{IP: "1.2.3.4"}}
+
+	changes, err := Object(service(3000), toUnstructured(t, live))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestObjectReportsChanges(t *testing.T) {
+	live := service(3000)
+	live.Labels["stale"] = "true"
+
+	changes, err := Object(service(8443), toUnstructured(t, live))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{Path: "metadata.labels.stale", Live: `"true"`},
+		{Path: "spec.ports[0].port", Live: "3000", Desired: "8443"},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+}
+
+func TestObjectComparesQuantities(t *testing.T) {
+	pod := func(cpu string) *v1.Pod {
+		return &v1.Pod{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+			ObjectMeta: metav1.ObjectMeta{Name: "p"},
+			Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name: "c",
+				Resources: v1.ResourceRequirements{
+					Requests: v1.ResourceList{v1.ResourceCPU: resource.MustParse(cpu)},
+				},
+			}}},
+		}
+	}
+
+	// The API server may return quantities in another notation.
+	live := toUnstructured(t, pod("500m"))
+	containers, _, _ := unstructured.NestedSlice(live.Object, "spec", "containers")
+	containers[0].(map[string]interface{})["resources"] = map[string]interface{}{
+		"requests": map[string]interface{}{"cpu": "0.5"},
+	}
+	if err := unstructured.SetNestedSlice(live.Object, containers, "spec", "containers"); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Object(pod("500m"), live)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes, got %v", changes)
+	}
+}
+
+func TestObjectHidesSecretData(t *testing.T) {
+	secret := func(v string) *v1.Secret {
+		return &v1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{Name: "s"},
+			Data:       map[string][]byte{"password": []byte(v)},
+		}
+	}
+
+	changes, err := Object(secret("new"), toUnstructured(t, secret("old")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{{Path: "data.password", Live: "(hidden)", Desired: "(hidden)"}}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+}
+
+type fakeGetter struct {
+	objects  []*unstructured.Unstructured
+	unserved map[string]bool
+}
+
+func (g *fakeGetter) GetUnstructured(gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error) {
+	for _, o := range g.objects {
+		if o.GetKind() == gvk.Kind && o.GetNamespace() == namespace && o.GetName() == name {
+			return o, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(schema.GroupResource{Resource: gvk.Kind}, name)
+}
+
+func (g *fakeGetter) ListUnstructured(gvk schema.GroupVersionKind, labelSelector string) (*unstructured.UnstructuredList, error) {
+	if g.unserved[gvk.Kind] {
+		return nil, &meta.NoKindMatchError{GroupKind: gvk.GroupKind()}
+	}
+	l := &unstructured.UnstructuredList{}
+	for _, o := range g.objects {
+		if o.GetKind() == gvk.Kind && o.GetLabels()[manifests.ManagedByLabel] == manifests.ManagedByValue {
+			l.Items = append(l.Items, *o)
+		}
+	}
+	return l, nil
+}
+
+func TestDiff(t *testing.T) {
+	orphan := service(9090)
+	orphan.Name = "old"
+	created := service(3000)
+	created.Name = "new"
+
+	g := &fakeGetter{objects: []*unstructured.Unstructured{
+		toUnstructured(t, service(3000)),
+		toUnstructured(t, orphan),
+	}}
+	components := []*render.Component{{
+		Name:    manifests.ComponentGrafana,
+		Objects: []runtime.Object{service(3000), created},
+	}}
+
+	results, err := Diff(components, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := map[string]Action{}
+	for _, r := range results {
+		actions[r.Name] = r.Action
+	}
+	expected := map[string]Action{"grafana": ActionNone, "new": ActionCreate, "old": ActionOrphan}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("expected %v, got %v", expected, actions)
+	}
+}
+
+func TestDiffKindNotRenderedAnymore(t *testing.T) {
+	etcd := func(kind string) *unstructured.Unstructured {
+		u := &unstructured.Unstructured{}
+		u.SetKind(kind)
+		u.SetNamespace("kube-system")
+		u.SetName("etcd")
+		u.SetLabels(map[string]string{
+			manifests.ManagedByLabel: manifests.ManagedByValue,
+			manifests.ComponentLabel: manifests.ComponentPrometheusK8s,
+		})
+		return u
+	}
+
+	// The etcd Endpoints and ServiceMonitor are left behind once etcd
+	// monitoring is disabled, and no other object of their kinds is
+	// rendered.
+	g := &fakeGetter{
+		objects: []*unstructured.Unstructured{
+			toUnstructured(t, service(3000)),
+			etcd("Endpoints"),
+			etcd("ServiceMonitor"),
+		},
+		unserved: map[string]bool{"Route": true},
+	}
+	components := []*render.Component{{
+		Name:    manifests.ComponentGrafana,
+		Objects: []runtime.Object{service(3000)},
+	}}
+
+	results, err := Diff(components, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actions := map[string]Action{}
+	for _, r := range results {
+		actions[r.Kind+"/"+r.Name] = r.Action
+	}
+	expected := map[string]Action{
+		"Service/grafana":     ActionNone,
+		"Endpoints/etcd":      ActionOrphan,
+		"ServiceMonitor/etcd": ActionOrphan,
+	}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Fatalf("expected %v, got %v", expected, actions)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package events records Kubernetes Events about the objects managed by the
+// operator. Repeated events are aggregated into a single Event whose count is
+// increased, like the event recorder of client-go does.
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/reference"
+)
+
+const (
+	// maxCachedEvents bounds the number of events remembered for
+	// aggregation.
+	maxCachedEvents = 4096
+)
+
+type Recorder struct {
+	client    kubernetes.Interface
+	namespace string
+	component string
+
+	// mtx only guards cache, so that recording events does not
+	// serialize the tasks recording them behind the API calls.
+	mtx   sync.Mutex
+	cache map[string]*v1.Event
+}
+
+// NewRecorder returns a Recorder creating events with the given component
+// as their source. Events about cluster-scoped objects are created in
+// namespace, the one of the operator.
+func NewRecorder(client kubernetes.Interface, namespace, component string) *Recorder {
+	return &Recorder{
+		client:    client,
+		namespace: namespace,
+		component: component,
+		cache:     map[string]*v1.Event{},
+	}
+}
+
+// Eventf records an event of type eventtype, either v1.EventTypeNormal or
+// v1.EventTypeWarning, about obj. Failures are logged, as events are
+// informational.
+func (r *Recorder) Eventf(obj runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	ref, err := reference.GetReference(scheme.Scheme, obj)
+	if err != nil {
+		glog.Errorf("Creating reference for event %q failed: %v", reason, err)
+		return
+	}
+
+	message := fmt.Sprintf(messageFmt, args...)
+	key := strings.Join([]string{ref.Kind, ref.Namespace, ref.Name, string(ref.UID), eventtype, reason, message}, "/")
+	now := metav1.Now()
+
+	r.mtx.Lock()
+	ev, ok := r.cache[key]
+	r.mtx.Unlock()
+
+	if ok {
+		ev = ev.DeepCopy()
+		ev.Count++
+		ev.LastTimestamp = now
+		updated, err := r.client.CoreV1().Events(ev.Namespace).Update(ev)
+		if err == nil {
+			r.cacheEvent(key, updated)
+			return
+		}
+		glog.V(4).Infof("Updating event %s/%s failed, creating a new one: %v", ev.Namespace, ev.Name, err)
+	}
+
+	namespace := ref.Namespace
+	if namespace == "" {
+		namespace = r.namespace
+	}
+
+	ev = &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", ref.Name, time.Now().UnixNano()),
+			Namespace: namespace,
+		},
+		InvolvedObject: *ref,
+		Reason:         reason,
+		Message:        message,
+		Type:           eventtype,
+		Count:          1,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Source:         v1.EventSource{Component: r.component},
+	}
+
+	created, err := r.client.CoreV1().Events(namespace).Create(ev)
+	if err != nil {
+		glog.Errorf("Creating event %q about %s %s/%s failed: %v", reason, ref.Kind, ref.Namespace, ref.Name, err)
+		return
+	}
+
+	r.cacheEvent(key, created)
+}
+
+// cacheEvent remembers the last version of an event for aggregation.
+func (r *Recorder) cacheEvent(key string, ev *v1.Event) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if len(r.cache) >= maxCachedEvents {
+		r.cache = map[string]*v1.Event{}
+	}
+	r.cache[key] = ev
+}
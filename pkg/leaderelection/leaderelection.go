@@ -0,0 +1,319 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leaderelection implements leader election between replicas of the
+// operator using a ConfigMap as the lock. The lock is compatible with the
+// ConfigMap lock of client-go: the leader is recorded in the
+// control-plane.alpha.kubernetes.io/leader annotation of the ConfigMap.
+package leaderelection
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+const (
+	// LeaderAnnotationKey is the annotation of the lock ConfigMap holding
+	// the leader election record.
+	LeaderAnnotationKey = "control-plane.alpha.kubernetes.io/leader"
+
+	jitterFactor = 1.2
+)
+
+// ErrLeadershipLost is returned by Run when the lease could not be renewed
+// within the renew deadline.
+var ErrLeadershipLost = errors.New("leadership lost")
+
+// Config configures leader election.
+type Config struct {
+	// Namespace and Name identify the ConfigMap used as the lock.
+	Namespace string
+	Name      string
+	// Identity uniquely identifies this replica, usually the pod name.
+	Identity string
+	// LeaseDuration is how long non-leaders wait before trying to acquire
+	// a lease that has not been renewed.
+	LeaseDuration time.Duration
+	// RenewDeadline is how long the leader retries renewing its lease
+	// before giving up leadership.
+	RenewDeadline time.Duration
+	// RetryPeriod is how long to wait between attempts to acquire or renew
+	// the lease.
+	RetryPeriod time.Duration
+}
+
+// Record is the leader election record stored in the lock. It is the same
+// as the LeaderElectionRecord of client-go.
+type Record struct {
+	HolderIdentity       string      `json:"holderIdentity"`
+	LeaseDurationSeconds int         `json:"leaseDurationSeconds"`
+	AcquireTime          metav1.Time `json:"acquireTime"`
+	RenewTime            metav1.Time `json:"renewTime"`
+	LeaderTransitions    int         `json:"leaderTransitions"`
+}
+
+type LeaderElector struct {
+	configMaps corev1client.ConfigMapInterface
+	config     Config
+	// now returns the current time. It is replaced in tests to expire
+	// leases without waiting.
+	now func() time.Time
+
+	observedRecord Record
+	observedTime   time.Time
+}
+
+func New(client kubernetes.Interface, config Config) (*LeaderElector, error) {
+	if config.Namespace == "" || config.Name == "" {
+		return nil, errors.New("lock namespace and name must not be empty")
+	}
+	if config.Identity == "" {
+		return nil, errors.New("identity must not be empty")
+	}
+	if config.LeaseDuration <= config.RenewDeadline {
+		return nil, errors.New("lease duration must be greater than renew deadline")
+	}
+	if config.RenewDeadline <= time.Duration(jitterFactor*float64(config.RetryPeriod)) {
+		return nil, errors.Errorf("renew deadline must be greater than retry period*%v", jitterFactor)
+	}
+	if config.RetryPeriod < 1 {
+		return nil, errors.New("retry period must be greater than zero")
+	}
+
+	return newLeaderElector(client.CoreV1().ConfigMaps(config.Namespace), config), nil
+}
+
+func newLeaderElector(configMaps corev1client.ConfigMapInterface, config Config) *LeaderElector {
+	return &LeaderElector{
+		configMaps: configMaps,
+		config:     config,
+		now:        time.Now,
+	}
+}
+
+// Run blocks until the lease is acquired and then calls run. The stop channel
+// passed to run is closed when leadership is lost or stopc is closed, and Run
+// always waits for run to return, so that nothing is done on behalf of this
+// replica once Run returned. If stopc was closed, Run then releases the
+// lease, so that another replica can take over right away, and returns nil.
+// If the lease could not be renewed, Run returns ErrLeadershipLost without
+// releasing it, as another replica may already be leading.
+func (le *LeaderElector) Run(stopc <-chan struct{}, run func(stopc <-chan struct{})) error {
+	if !le.acquire(stopc) {
+		return nil
+	}
+
+	leadingc := make(chan struct{})
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		run(leadingc)
+	}()
+
+	err := le.renew(stopc)
+	close(leadingc)
+	<-donec
+	if err != nil {
+		return err
+	}
+
+	le.release()
+	return nil
+}
+
+// acquire tries to acquire the lease until it succeeds or stopc is closed.
+// It returns true if the lease was acquired.
+func (le *LeaderElector) acquire(stopc <-chan struct{}) bool {
+	glog.V(4).Infof("Attempting to acquire leader lease %s/%s...", le.config.Namespace, le.config.Name)
+
+	acquired := false
+	stop := make(chan struct{})
+	wait.JitterUntil(func() {
+		if !le.tryAcquireOrRenew() {
+			glog.V(4).Infof("Leader lease is held by %s", le.observedRecord.HolderIdentity)
+			return
+		}
+		acquired = true
+		close(stop)
+	}, le.config.RetryPeriod, jitterFactor, true, mergeStop(stopc, stop))
+
+	if acquired {
+		glog.V(4).Infof("Successfully acquired leader lease %s/%s", le.config.Namespace, le.config.Name)
+	}
+	return acquired
+}
+
+// renew keeps renewing the lease until stopc is closed or the lease could not
+// be renewed within the renew deadline.
+func (le *LeaderElector) renew(stopc <-chan struct{}) error {
+	t := time.NewTicker(le.config.RetryPeriod)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stopc:
+			return nil
+		case <-t.C:
+		}
+
+		err := wait.Poll(le.config.RetryPeriod/2, le.config.RenewDeadline, func() (bool, error) {
+			return le.tryAcquireOrRenew(), nil
+		})
+		if err != nil {
+			glog.Errorf("Failed to renew leader lease %s/%s: %v", le.config.Namespace, le.config.Name, err)
+			return ErrLeadershipLost
+		}
+	}
+}
+
+// release gives up the lease if it is held by this replica.
+func (le *LeaderElector) release() {
+	if le.observedRecord.HolderIdentity != le.config.Identity {
+		return
+	}
+
+	cm, err := le.configMaps.Get(le.config.Name, metav1.GetOptions{})
+	if err != nil {
+		glog.Errorf("Failed to release leader lease: %v", err)
+		return
+	}
+
+	now := metav1.NewTime(le.now())
+	r := Record{
+		LeaseDurationSeconds: 1,
+		AcquireTime:          now,
+		RenewTime:            now,
+		LeaderTransitions:    le.observedRecord.LeaderTransitions,
+	}
+	if err := le.update(cm, r); err != nil {
+		glog.Errorf("Failed to release leader lease: %v", err)
+		return
+	}
+	glog.V(4).Infof("Released leader lease %s/%s", le.config.Namespace, le.config.Name)
+}
+
+// tryAcquireOrRenew acquires the lease if it is free or expired, or renews it
+// if it is already held by this replica. It returns true on success.
+func (le *LeaderElector) tryAcquireOrRenew() bool {
+	now := metav1.NewTime(le.now())
+	r := Record{
+		HolderIdentity:       le.config.Identity,
+		LeaseDurationSeconds: int(le.config.LeaseDuration / time.Second),
+		AcquireTime:          now,
+		RenewTime:            now,
+	}
+
+	cms := le.configMaps
+	cm, err := cms.Get(le.config.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		b, err := json.Marshal(r)
+		if err != nil {
+			glog.Errorf("Encoding leader election record failed: %v", err)
+			return false
+		}
+		_, err = cms.Create(&v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        le.config.Name,
+				Namespace:   le.config.Namespace,
+				Annotations: map[string]string{LeaderAnnotationKey: string(b)},
+			},
+		})
+		if err != nil {
+			glog.Errorf("Creating leader lease failed: %v", err)
+			return false
+		}
+		le.observe(r)
+		return true
+	}
+	if err != nil {
+		glog.Errorf("Retrieving leader lease failed: %v", err)
+		return false
+	}
+
+	old := Record{}
+	if v, ok := cm.Annotations[LeaderAnnotationKey]; ok {
+		if err := json.Unmarshal([]byte(v), &old); err != nil {
+			glog.Errorf("Decoding leader election record failed: %v", err)
+			return false
+		}
+	}
+
+	if !reflect.DeepEqual(le.observedRecord, old) {
+		le.observedRecord = old
+		le.observedTime = le.now()
+	}
+	if old.HolderIdentity != "" && old.HolderIdentity != le.config.Identity &&
+		le.observedTime.Add(time.Duration(old.LeaseDurationSeconds)*time.Second).After(le.now()) {
+		return false
+	}
+
+	if old.HolderIdentity == le.config.Identity {
+		r.AcquireTime = old.AcquireTime
+		r.LeaderTransitions = old.LeaderTransitions
+	} else {
+		r.LeaderTransitions = old.LeaderTransitions + 1
+	}
+
+	if err := le.update(cm, r); err != nil {
+		glog.Errorf("Updating leader lease failed: %v", err)
+		return false
+	}
+	le.observe(r)
+	return true
+}
+
+func (le *LeaderElector) update(cm *v1.ConfigMap, r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return errors.Wrap(err, "encoding leader election record failed")
+	}
+
+	cm = cm.DeepCopy()
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[LeaderAnnotationKey] = string(b)
+
+	_, err = le.configMaps.Update(cm)
+	return err
+}
+
+func (le *LeaderElector) observe(r Record) {
+	le.observedRecord = r
+	le.observedTime = le.now()
+}
+
+// mergeStop returns a channel that is closed as soon as one of a or b is
+// closed.
+func mergeStop(a, b <-chan struct{}) <-chan struct{} {
+	c := make(chan struct{})
+	go func() {
+		defer close(c)
+		select {
+		case <-a:
+		case <-b:
+		}
+	}()
+	return c
+}
@@ -0,0 +1,252 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leaderelection
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// fakeConfigMaps stores a single lock ConfigMap in memory. Only the methods
+// used by the LeaderElector are implemented.
+type fakeConfigMaps struct {
+	corev1client.ConfigMapInterface
+
+	mtx         sync.Mutex
+	cm          *v1.ConfigMap
+	failUpdates bool
+}
+
+func (f *fakeConfigMaps) Get(name string, options metav1.GetOptions) (*v1.ConfigMap, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.cm == nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, name)
+	}
+	return f.cm.DeepCopy(), nil
+}
+
+func (f *fakeConfigMaps) Create(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.cm != nil {
+		return nil, apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, cm.Name)
+	}
+	f.cm = cm.DeepCopy()
+	return cm, nil
+}
+
+func (f *fakeConfigMaps) Update(cm *v1.ConfigMap) (*v1.ConfigMap, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.failUpdates {
+		return nil, errors.New("update failed")
+	}
+	if f.cm == nil {
+		return nil, apierrors.NewNotFound(schema.GroupResource{Resource: "configmaps"}, cm.Name)
+	}
+	f.cm = cm.DeepCopy()
+	return cm, nil
+}
+
+func (f *fakeConfigMaps) setFailUpdates(fail bool) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.failUpdates = fail
+}
+
+func (f *fakeConfigMaps) record(t *testing.T) Record {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.cm == nil {
+		t.Fatal("expected the lock ConfigMap to exist")
+	}
+	r := Record{}
+	if err := json.Unmarshal([]byte(f.cm.Annotations[LeaderAnnotationKey]), &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+// clock is a time shared by the electors of a test, advanced by hand.
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func newTestElector(cms *fakeConfigMaps, c *clock, identity string) *LeaderElector {
+	le := newLeaderElector(cms, Config{
+		Namespace:     "openshift-monitoring",
+		Name:          "cluster-monitoring-operator-lock",
+		Identity:      identity,
+		LeaseDuration: 15 * time.Second,
+		RenewDeadline: 50 * time.Millisecond,
+		RetryPeriod:   10 * time.Millisecond,
+	})
+	if c != nil {
+		le.now = c.now
+	}
+	return le
+}
+
+func TestAcquireAndRenew(t *testing.T) {
+	cms := &fakeConfigMaps{}
+	c := &clock{t: time.Unix(1000, 0)}
+	a := newTestElector(cms, c, "a")
+
+	if !a.tryAcquireOrRenew() {
+		t.Fatal("expected the free lease to be acquired")
+	}
+	acquired := cms.record(t)
+	if acquired.HolderIdentity != "a" || acquired.LeaseDurationSeconds != 15 || acquired.LeaderTransitions != 0 {
+		t.Fatalf("unexpected record after acquiring %+v", acquired)
+	}
+
+	c.t = c.t.Add(10 * time.Second)
+	if !a.tryAcquireOrRenew() {
+		t.Fatal("expected the lease to be renewed")
+	}
+	renewed := cms.record(t)
+	if renewed.HolderIdentity != "a" || renewed.LeaderTransitions != 0 {
+		t.Errorf("unexpected record after renewing %+v", renewed)
+	}
+	if !renewed.AcquireTime.Equal(&acquired.AcquireTime) {
+		t.Errorf("expected the acquire time %v to be kept, got %v", acquired.AcquireTime, renewed.AcquireTime)
+	}
+	if renewed.RenewTime.Unix() != c.t.Unix() {
+		t.Errorf("expected the renew time to be %v, got %v", c.t, renewed.RenewTime)
+	}
+}
+
+func TestTakeoverAfterExpiry(t *testing.T) {
+	cms := &fakeConfigMaps{}
+	c := &clock{t: time.Unix(1000, 0)}
+	a := newTestElector(cms, c, "a")
+	b := newTestElector(cms, c, "b")
+
+	if !a.tryAcquireOrRenew() {
+		t.Fatal("expected the free lease to be acquired")
+	}
+	if b.tryAcquireOrRenew() {
+		t.Fatal("expected the lease held by a not to be acquired")
+	}
+
+	// The lease is not expired until the lease duration passed since b
+	// first observed it.
+	c.t = c.t.Add(14 * time.Second)
+	if b.tryAcquireOrRenew() {
+		t.Fatal("expected the lease not to be acquired before it expired")
+	}
+
+	c.t = c.t.Add(2 * time.Second)
+	if !b.tryAcquireOrRenew() {
+		t.Fatal("expected the expired lease to be taken over")
+	}
+	if r := cms.record(t); r.HolderIdentity != "b" || r.LeaderTransitions != 1 {
+		t.Errorf("unexpected record after taking over %+v", r)
+	}
+	if a.tryAcquireOrRenew() {
+		t.Error("expected the lease taken over by b not to be renewed by a")
+	}
+}
+
+func TestRunReleasesLease(t *testing.T) {
+	cms := &fakeConfigMaps{}
+	a := newTestElector(cms, nil, "a")
+
+	stopc := make(chan struct{})
+	started := make(chan struct{})
+	var finished int32
+	errc := make(chan error)
+	go func() {
+		errc <- a.Run(stopc, func(leading <-chan struct{}) {
+			close(started)
+			<-leading
+			atomic.StoreInt32(&finished, 1)
+		})
+	}()
+
+	<-started
+	if r := cms.record(t); r.HolderIdentity != "a" {
+		t.Fatalf("expected a to hold the lease, got %+v", r)
+	}
+	close(stopc)
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&finished) != 1 {
+		t.Error("expected Run to wait for run to return")
+	}
+
+	if r := cms.record(t); r.HolderIdentity != "" {
+		t.Errorf("expected the lease to be released, got %+v", r)
+	}
+	if !newTestElector(cms, nil, "b").tryAcquireOrRenew() {
+		t.Error("expected the released lease to be acquired right away")
+	}
+}
+
+func TestRunLeadershipLost(t *testing.T) {
+	cms := &fakeConfigMaps{}
+	a := newTestElector(cms, nil, "a")
+
+	lost := make(chan struct{})
+	proceed := make(chan struct{})
+	var finished int32
+	type result struct {
+		err      error
+		finished bool
+	}
+	resc := make(chan result)
+	go func() {
+		err := a.Run(make(chan struct{}), func(leading <-chan struct{}) {
+			// The lease cannot be renewed from now on.
+			cms.setFailUpdates(true)
+			<-leading
+			close(lost)
+			<-proceed
+			atomic.StoreInt32(&finished, 1)
+		})
+		resc <- result{err: err, finished: atomic.LoadInt32(&finished) == 1}
+	}()
+
+	select {
+	case res := <-resc:
+		t.Fatalf("expected Run to wait for run to return, got %v", res.err)
+	case <-lost:
+	}
+	close(proceed)
+
+	res := <-resc
+	if res.err != ErrLeadershipLost {
+		t.Errorf("expected ErrLeadershipLost, got %v", res.err)
+	}
+	if !res.finished {
+		t.Error("expected Run to return after run")
+	}
+}
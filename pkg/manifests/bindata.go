@@ -3,12 +3,15 @@
 // assets/alertmanager/alertmanager.yaml
 // assets/alertmanager/cluster-role-binding.yaml
 // assets/alertmanager/cluster-role.yaml
+// assets/alertmanager/ingress.yaml
 // assets/alertmanager/proxy-secret.yaml
 // assets/alertmanager/route.yaml
 // assets/alertmanager/secret.yaml
 // assets/alertmanager/service-account.yaml
 // assets/alertmanager/service-monitor.yaml
 // assets/alertmanager/service.yaml
+// assets/cluster-monitoring-operator/service-monitor.yaml
+// assets/cluster-monitoring-operator/service.yaml
 // assets/grafana/cluster-role-binding.yaml
 // assets/grafana/cluster-role.yaml
 // assets/grafana/config.yaml
@@ -16,6 +19,7 @@
 // assets/grafana/dashboard-definitions.yaml
 // assets/grafana/dashboard-sources.yaml
 // assets/grafana/deployment.yaml
+// assets/grafana/ingress.yaml
 // assets/grafana/proxy-secret.yaml
 // assets/grafana/route.yaml
 // assets/grafana/service-account.yaml
@@ -39,6 +43,7 @@
 // assets/prometheus-k8s/cluster-role.yaml
 // assets/prometheus-k8s/endpoints-etcd.yaml
 // assets/prometheus-k8s/htpasswd-secret.yaml
+// assets/prometheus-k8s/ingress.yaml
 // assets/prometheus-k8s/kube-controllers-service.yaml
 // assets/prometheus-k8s/prometheus.yaml
 // assets/prometheus-k8s/proxy-secret.yaml
@@ -193,6 +198,26 @@ func assetsAlertmanagerClusterRoleYaml() (*asset, error) {
 	return a, nil
 }
 
+var _assetsAlertmanagerIngressYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x75\x4e\x41\x0e\xc2\x30\x0c\xbb\xf7\x15\xf9\x40\x35\xed\xda\x1f\x70\x41\x9c\xb8\x67\x5d\xd8\x22\xd6\xb4\x6a\xc2\xe0\xf9\xb4\xd2\xe0\x80\x84\x4f\x8e\x1d\xd9\xc6\xc2\x57\xaa\xca\x59\x02\xd0\xcb\x48\x3a\xd5\x61\x1f\x27\x32\x1c\xdd\x9d\x65\x0e\x70\x92\xa5\x92\xaa\x4b\x4d\x9b\xd1\x30\x38\x00\xc1\x44\x01\x70\xa3\x6a\x09\x05\x17\xaa\x3e\x21\xcb\xe1\x68\xc1\xd8\xec\x5c\x5a\xe0\xca\x37\xf3\x29\x0b\x5b\xae\x2c\x8b\xd3\x42\xb1\x27\xd4\xc7\x46\xda\x89\x87\xd5\xac\x74\xd6\x51\xd0\x56\xfd\x1c\x1e\x26\x8c\x77\x6a\x23\x0e\xa1\x43\xa9\xee\x1c\xe9\xfc\x77\xc1\xcf\xe3\x25\x57\x0b\xf0\xa4\xe9\x6b\xf5\x8e\x00\x83\x7b\x03\xd1\x06\x72\xa9\xfe\x00\x00\x00")
+
+func assetsAlertmanagerIngressYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_assetsAlertmanagerIngressYaml,
+		"assets/alertmanager/ingress.yaml",
+	)
+}
+
+func assetsAlertmanagerIngressYaml() (*asset, error) {
+	bytes, err := assetsAlertmanagerIngressYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "assets/alertmanager/ingress.yaml", size: 254, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _assetsAlertmanagerProxySecretYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6c\xc9\x31\xae\xc2\x30\x0c\x06\xe0\xdd\xa7\xf0\x05\x32\xbc\xed\x29\x97\x60\x40\x62\xff\x69\x4d\xb1\x9a\x38\x26\x31\x88\x0a\x71\x77\x04\x62\x64\xfb\xa4\x0f\xae\x07\xe9\x43\x9b\x65\xbe\xfd\xd1\x8c\x40\xe6\xc7\x93\x56\xb5\x39\xf3\x5e\xa6\x2e\x41\x55\x02\x9f\x21\xe6\x82\xa3\x94\xf1\x16\xf3\xfa\x3f\x12\xdc\x33\xa3\x48\x8f\x0a\xc3\x22\x3d\x55\xa8\x11\xb3\xa1\xca\x8f\x49\xde\xdb\x7d\xfb\xfe\x70\x4c\x92\xb9\xb9\xd8\x38\xeb\x29\x52\x6d\xa6\xd1\xba\xda\x42\xb1\xb9\x64\xde\x39\x2e\x57\xa1\x57\x00\x00\x00\xff\xff\x3d\x78\x6d\x37\xa7\x00\x00\x00")
 
 func assetsAlertmanagerProxySecretYamlBytes() ([]byte, error) {
@@ -313,6 +338,46 @@ func assetsAlertmanagerServiceYaml() (*asset, error) {
 	return a, nil
 }
 
+var _assetsClusterMonitoringOperatorServiceMonitorYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x8e\x31\x0e\xc2\x30\x0c\x45\xf7\x9c\xc2\x17\x28\x88\x0d\xe5\x0c\x30\x21\xb1\x9b\xd4\x50\xab\x49\x6c\xc5\xa6\xe7\x27\xa5\x03\x6c\x30\xd9\xfe\x7a\xb6\x1f\x2a\x5f\xa9\x19\x4b\x8d\x50\xa4\xb2\x4b\xe3\xfa\xd8\x25\x69\x24\xd6\x4b\xd9\x2f\x87\x30\x73\x1d\x23\x5c\xa8\x2d\x9c\xe8\xbc\x51\xa1\x90\xe3\x88\x8e\x31\x00\x64\xbc\x51\xb6\xb5\x03\x98\x8f\x36\xa0\x6a\x84\x94\x9f\xe6\xd4\x86\xcf\xd9\x41\x94\x1a\xae\xcb\x00\x15\x0b\xfd\xc3\x98\x62\xea\x60\x4f\xab\x4d\x7c\xf7\x2f\x34\x98\x52\x5a\x9f\x52\x1d\x55\xb8\xfa\xdb\x60\x00\x95\xe6\x11\x26\x77\xed\xa3\x51\xa6\xd4\xf9\x4d\xae\xa0\xa7\xe9\xf4\x65\x0b\xf0\xd3\xf5\x05\xe9\xa4\x11\xb9\x23\x01\x00\x00")
+
+func assetsClusterMonitoringOperatorServiceMonitorYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_assetsClusterMonitoringOperatorServiceMonitorYaml,
+		"assets/cluster-monitoring-operator/service-monitor.yaml",
+	)
+}
+
+func assetsClusterMonitoringOperatorServiceMonitorYaml() (*asset, error) {
+	bytes, err := assetsClusterMonitoringOperatorServiceMonitorYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "assets/cluster-monitoring-operator/service-monitor.yaml", size: 291, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
+var _assetsClusterMonitoringOperatorServiceYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x8d\x8f\x31\x0e\x83\x30\x0c\x45\xf7\x9c\xc2\x17\x40\xa2\x1b\xca\x0d\xba\x54\x48\x95\xba\xbb\xc1\x85\xa8\xc1\xb1\x62\x97\xf3\x13\x22\x86\x8e\x6c\xdf\xdf\x4f\xfe\xdf\x28\xf1\x45\x45\x63\x66\x0f\xdb\xcd\x7d\x23\x4f\x1e\x9e\x54\xb6\x18\xc8\xad\x64\x38\xa1\xa1\x77\x00\x09\xdf\x94\xf4\x50\x00\x28\xe2\x21\xa4\x9f\x1a\x95\x6e\xcd\x1c\x2d\x97\xc8\x73\x97\x85\x0a\x56\x5d\x21\xc6\x95\xae\x30\x2a\x18\x2a\x58\x5d\xd6\x25\x7e\xec\x0f\x75\x2a\x14\x8e\xc0\xf3\xca\x7d\xf4\xf0\xc8\x4c\xd5\x91\x5c\xac\x75\xe9\xce\xa0\xc5\x4c\x5a\xb5\x63\xe3\x61\xe8\x87\xbe\x8d\x86\x65\x26\x1b\x9b\x79\x32\x4a\x89\x42\x4d\xb8\xf8\xca\x0e\xb9\x7c\xb1\x31\x21\x01\x00\x00")
+
+func assetsClusterMonitoringOperatorServiceYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_assetsClusterMonitoringOperatorServiceYaml,
+		"assets/cluster-monitoring-operator/service.yaml",
+	)
+}
+
+func assetsClusterMonitoringOperatorServiceYaml() (*asset, error) {
+	bytes, err := assetsClusterMonitoringOperatorServiceYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "assets/cluster-monitoring-operator/service.yaml", size: 289, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _assetsGrafanaClusterRoleBindingYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x7c\x8e\x3d\x8e\x83\x40\x0c\x85\xfb\x39\x85\x2f\x00\xab\xed\x56\xd3\xed\x6e\x91\x9e\x48\xe9\xcd\x60\xc0\x01\xec\x91\xc7\x43\x91\xd3\x47\x28\xe9\x90\x52\x3e\xbd\x9f\xef\x61\xe6\x1b\x59\x61\x95\x08\xd6\x63\x6a\xb1\xfa\xac\xc6\x0f\x74\x56\x69\x97\x9f\xd2\xb2\x7e\xed\xdf\x61\x61\x19\x22\xfc\xaf\xb5\x38\x59\xa7\x2b\xfd\xb1\x0c\x2c\x53\xd8\xc8\x71\x40\xc7\x18\x00\x04\x37\x8a\x30\x19\x8e\x28\x18\x4c\x57\xea\x68\x3c\x0c\xcc\x7c\x31\xad\xf9\x03\x24\x00\x9c\x18\xa7\xc9\x52\xfb\x3b\x25\x2f\x31\x34\xef\xf4\x95\x6c\xe7\x44\xbf\x29\x69\x15\x3f\x15\x5e\xba\x64\x4c\x14\x41\x33\x49\x99\x79\xf4\x66\x53\x61\x57\x3b\xfe\x3f\x03\x00\x00\xff\xff\x34\xc3\x5d\xe6\x02\x01\x00\x00")
 
 func assetsGrafanaClusterRoleBindingYamlBytes() ([]byte, error) {
@@ -453,6 +518,26 @@ func assetsGrafanaDeploymentYaml() (*asset, error) {
 	return a, nil
 }
 
+var _assetsGrafanaIngressYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x5d\x4d\xbb\x0e\x83\x30\x0c\xdc\xf3\x15\xfe\x01\x84\x58\xf3\x07\x5d\xaa\x4e\xdd\x4d\x30\x60\x51\x9c\xc8\x76\x51\x3f\xbf\x49\x45\x3b\xf4\xa6\x7b\xe8\xee\xb0\xf0\x9d\xd4\x38\x4b\x04\x7a\x39\x49\xa3\xd6\x1f\xc3\x48\x8e\x43\xd8\x58\xa6\x08\x17\x59\x94\xcc\xc2\x5e\xbd\x09\x1d\x63\x00\x10\xdc\x29\xc2\xa2\x38\xa3\xe0\xa9\xad\x60\xaa\x66\x2e\x75\x66\xe5\xd9\xbb\x3d\x0b\x7b\x56\x96\x25\x58\xa1\xd4\x7a\xfa\x7c\x90\x35\xd2\xc1\xea\x5e\x1a\x6b\x28\xe8\xab\x7d\x45\x07\x23\xa6\x8d\xea\xf5\x69\x34\x18\xe9\xc1\x89\xae\x7f\xbf\x7f\xf1\x2d\xab\xc7\xcf\xb2\xfd\xc2\xb6\x1d\xa1\x0f\x6f\x89\x8d\x33\xf6\xec\x00\x00\x00")
+
+func assetsGrafanaIngressYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_assetsGrafanaIngressYaml,
+		"assets/grafana/ingress.yaml",
+	)
+}
+
+func assetsGrafanaIngressYaml() (*asset, error) {
+	bytes, err := assetsGrafanaIngressYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "assets/grafana/ingress.yaml", size: 236, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _assetsGrafanaProxySecretYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x3c\x89\x3d\x0a\xc2\x40\x10\x85\xfb\x39\xc5\xbb\x40\x0a\x3b\xd9\x4b\x58\x08\xf6\xcf\x64\x12\x97\x24\xb3\xe3\xee\x28\x06\xf1\xee\xa2\x88\xdd\xf7\x43\xcf\x27\xad\x2d\x17\x4b\xb8\xef\x64\x60\x30\xe1\xf9\x92\x39\xdb\x90\x70\xd4\xbe\x6a\xc8\xaa\xc1\xef\x11\x60\xe1\x59\x97\xf6\x21\x60\xde\xb7\x8e\xee\x09\x53\xe5\x48\xa3\x00\xc6\x55\xff\xde\x79\x2d\x8f\xed\x57\x9b\xb3\xd7\x84\xe2\x6a\xed\x92\xc7\xe8\xd6\x62\x39\x4a\xcd\x36\x49\x6c\xae\x09\x07\xe7\xf5\xa6\xf2\x0e\x00\x00\xff\xff\x6d\x14\xc9\x87\x93\x00\x00\x00")
 
 func assetsGrafanaProxySecretYamlBytes() ([]byte, error) {
@@ -913,6 +998,26 @@ func assetsPrometheusK8sHtpasswdSecretYaml() (*asset, error) {
 	return a, nil
 }
 
+var _assetsPrometheusK8sIngressYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x6d\x8e\x31\x0e\xc2\x30\x0c\x45\xf7\x9c\xc2\x17\x88\xaa\x6e\x28\x37\x60\x41\x4c\xec\x6e\x6a\x9a\x28\xd4\x89\x62\xb7\x70\x7c\x12\xa9\x30\x20\x3c\x7d\xbf\x6f\xfb\x1b\x4b\xbc\x51\x95\x98\xd9\x01\xbd\x94\xb8\x4b\x19\xf6\x71\x22\xc5\xd1\xa4\xc8\xb3\x83\x33\x2f\x95\x44\xcc\xda\xd8\x8c\x8a\xce\x00\x30\xae\xe4\xa0\xd4\xdc\x60\xa0\x4d\x6c\x3a\xc9\x81\xa5\xa0\x6f\x5e\x2e\xed\x5a\x88\x77\xb5\x6b\xe6\xa8\xb9\x46\x5e\x8c\x14\xf2\x7d\xbd\x6e\x0f\x92\x2e\x2c\x04\xd5\xd2\x55\xaf\x82\x1a\xe4\xd3\x58\x98\xd0\x27\x6a\x1f\x1c\xa0\x97\x50\xdd\xa3\xa7\xcb\xff\xf8\x9f\xa9\x6b\xae\xea\xe0\x49\xd3\xd7\xea\x01\x0e\x06\xf3\x06\xdc\xd4\x28\x76\xf8\x00\x00\x00")
+
+func assetsPrometheusK8sIngressYamlBytes() ([]byte, error) {
+	return bindataRead(
+		_assetsPrometheusK8sIngressYaml,
+		"assets/prometheus-k8s/ingress.yaml",
+	)
+}
+
+func assetsPrometheusK8sIngressYaml() (*asset, error) {
+	bytes, err := assetsPrometheusK8sIngressYamlBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	info := bindataFileInfo{name: "assets/prometheus-k8s/ingress.yaml", size: 248, mode: os.FileMode(420), modTime: time.Unix(1, 0)}
+	a := &asset{bytes: bytes, info: info}
+	return a, nil
+}
+
 var _assetsPrometheusK8sKubeControllersServiceYaml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x00\xff\x74\x8e\x41\x4a\x04\x41\x0c\x45\xf7\x75\x8a\x30\xfb\x56\x84\x5e\x0c\x75\x03\x37\x32\x20\xb8\xcf\xd4\x7c\x9d\xa2\xab\x2a\x21\x49\x0f\x78\x7b\xe9\x56\x41\x11\x77\x49\xde\xe7\xe7\xb1\xd6\x17\x98\x57\x19\x99\x6e\x0f\x69\xa9\xe3\x92\xe9\x19\x76\xab\x05\xa9\x23\xf8\xc2\xc1\x39\x11\x35\x3e\xa3\xf9\x36\x11\x2d\x47\x9f\x58\x35\xd3\xb2\x9e\x31\x15\x19\x61\xd2\x1a\xcc\x13\xd1\xe0\x8e\x7f\x81\x2b\x97\x6f\xea\xef\x1e\xe8\xc9\x15\x65\x6b\x2d\x6d\xf5\x80\x3d\x9e\x32\x3d\xc9\x40\x22\x52\xb1\xd8\x1f\x4e\x5f\xa5\xd7\x08\x9d\x3a\xc2\x6a\xf1\xdd\x63\x4b\x64\x3a\xce\xf3\xbc\xaf\xc1\xf6\x86\x38\xfd\x3c\x3a\x1a\x4a\x88\x7d\x7a\x8b\x62\xf8\xb5\xbe\xc6\x5d\x95\xfb\x22\x5d\x65\x60\x44\xa6\xdf\xa2\x7f\x72\x3b\x9c\xb4\xf1\x40\xa6\x43\xd8\x8a\x43\xfa\x08\x00\x00\xff\xff\x08\x0c\x53\x1b\x38\x01\x00\x00")
 
 func assetsPrometheusK8sKubeControllersServiceYamlBytes() ([]byte, error) {
@@ -1508,12 +1613,15 @@ var _bindata = map[string]func() (*asset, error){
 	"assets/alertmanager/alertmanager.yaml": assetsAlertmanagerAlertmanagerYaml,
 	"assets/alertmanager/cluster-role-binding.yaml": assetsAlertmanagerClusterRoleBindingYaml,
 	"assets/alertmanager/cluster-role.yaml": assetsAlertmanagerClusterRoleYaml,
+	"assets/alertmanager/ingress.yaml": assetsAlertmanagerIngressYaml,
 	"assets/alertmanager/proxy-secret.yaml": assetsAlertmanagerProxySecretYaml,
 	"assets/alertmanager/route.yaml": assetsAlertmanagerRouteYaml,
 	"assets/alertmanager/secret.yaml": assetsAlertmanagerSecretYaml,
 	"assets/alertmanager/service-account.yaml": assetsAlertmanagerServiceAccountYaml,
 	"assets/alertmanager/service-monitor.yaml": assetsAlertmanagerServiceMonitorYaml,
 	"assets/alertmanager/service.yaml": assetsAlertmanagerServiceYaml,
+	"assets/cluster-monitoring-operator/service-monitor.yaml": assetsClusterMonitoringOperatorServiceMonitorYaml,
+	"assets/cluster-monitoring-operator/service.yaml": assetsClusterMonitoringOperatorServiceYaml,
 	"assets/grafana/cluster-role-binding.yaml": assetsGrafanaClusterRoleBindingYaml,
 	"assets/grafana/cluster-role.yaml": assetsGrafanaClusterRoleYaml,
 	"assets/grafana/config.yaml": assetsGrafanaConfigYaml,
@@ -1521,6 +1629,7 @@ var _bindata = map[string]func() (*asset, error){
 	"assets/grafana/dashboard-definitions.yaml": assetsGrafanaDashboardDefinitionsYaml,
 	"assets/grafana/dashboard-sources.yaml": assetsGrafanaDashboardSourcesYaml,
 	"assets/grafana/deployment.yaml": assetsGrafanaDeploymentYaml,
+	"assets/grafana/ingress.yaml": assetsGrafanaIngressYaml,
 	"assets/grafana/proxy-secret.yaml": assetsGrafanaProxySecretYaml,
 	"assets/grafana/route.yaml": assetsGrafanaRouteYaml,
 	"assets/grafana/service-account.yaml": assetsGrafanaServiceAccountYaml,
@@ -1544,6 +1653,7 @@ var _bindata = map[string]func() (*asset, error){
 	"assets/prometheus-k8s/cluster-role.yaml": assetsPrometheusK8sClusterRoleYaml,
 	"assets/prometheus-k8s/endpoints-etcd.yaml": assetsPrometheusK8sEndpointsEtcdYaml,
 	"assets/prometheus-k8s/htpasswd-secret.yaml": assetsPrometheusK8sHtpasswdSecretYaml,
+	"assets/prometheus-k8s/ingress.yaml": assetsPrometheusK8sIngressYaml,
 	"assets/prometheus-k8s/kube-controllers-service.yaml": assetsPrometheusK8sKubeControllersServiceYaml,
 	"assets/prometheus-k8s/prometheus.yaml": assetsPrometheusK8sPrometheusYaml,
 	"assets/prometheus-k8s/proxy-secret.yaml": assetsPrometheusK8sProxySecretYaml,
@@ -1618,6 +1728,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"alertmanager.yaml": &bintree{assetsAlertmanagerAlertmanagerYaml, map[string]*bintree{}},
 			"cluster-role-binding.yaml": &bintree{assetsAlertmanagerClusterRoleBindingYaml, map[string]*bintree{}},
 			"cluster-role.yaml": &bintree{assetsAlertmanagerClusterRoleYaml, map[string]*bintree{}},
+			"ingress.yaml": &bintree{assetsAlertmanagerIngressYaml, map[string]*bintree{}},
 			"proxy-secret.yaml": &bintree{assetsAlertmanagerProxySecretYaml, map[string]*bintree{}},
 			"route.yaml": &bintree{assetsAlertmanagerRouteYaml, map[string]*bintree{}},
 			"secret.yaml": &bintree{assetsAlertmanagerSecretYaml, map[string]*bintree{}},
@@ -1625,6 +1736,10 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"service-monitor.yaml": &bintree{assetsAlertmanagerServiceMonitorYaml, map[string]*bintree{}},
 			"service.yaml": &bintree{assetsAlertmanagerServiceYaml, map[string]*bintree{}},
 		}},
+		"cluster-monitoring-operator": &bintree{nil, map[string]*bintree{
+			"service-monitor.yaml": &bintree{assetsClusterMonitoringOperatorServiceMonitorYaml, map[string]*bintree{}},
+			"service.yaml": &bintree{assetsClusterMonitoringOperatorServiceYaml, map[string]*bintree{}},
+		}},
 		"grafana": &bintree{nil, map[string]*bintree{
 			"cluster-role-binding.yaml": &bintree{assetsGrafanaClusterRoleBindingYaml, map[string]*bintree{}},
 			"cluster-role.yaml": &bintree{assetsGrafanaClusterRoleYaml, map[string]*bintree{}},
@@ -1633,6 +1748,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"dashboard-definitions.yaml": &bintree{assetsGrafanaDashboardDefinitionsYaml, map[string]*bintree{}},
 			"dashboard-sources.yaml": &bintree{assetsGrafanaDashboardSourcesYaml, map[string]*bintree{}},
 			"deployment.yaml": &bintree{assetsGrafanaDeploymentYaml, map[string]*bintree{}},
+			"ingress.yaml": &bintree{assetsGrafanaIngressYaml, map[string]*bintree{}},
 			"proxy-secret.yaml": &bintree{assetsGrafanaProxySecretYaml, map[string]*bintree{}},
 			"route.yaml": &bintree{assetsGrafanaRouteYaml, map[string]*bintree{}},
 			"service-account.yaml": &bintree{assetsGrafanaServiceAccountYaml, map[string]*bintree{}},
@@ -1662,6 +1778,7 @@ var _bintree = &bintree{nil, map[string]*bintree{
 			"cluster-role.yaml": &bintree{assetsPrometheusK8sClusterRoleYaml, map[string]*bintree{}},
 			"endpoints-etcd.yaml": &bintree{assetsPrometheusK8sEndpointsEtcdYaml, map[string]*bintree{}},
 			"htpasswd-secret.yaml": &bintree{assetsPrometheusK8sHtpasswdSecretYaml, map[string]*bintree{}},
+			"ingress.yaml": &bintree{assetsPrometheusK8sIngressYaml, map[string]*bintree{}},
 			"kube-controllers-service.yaml": &bintree{assetsPrometheusK8sKubeControllersServiceYaml, map[string]*bintree{}},
 			"prometheus.yaml": &bintree{assetsPrometheusK8sPrometheusYaml, map[string]*bintree{}},
 			"proxy-secret.yaml": &bintree{assetsPrometheusK8sProxySecretYaml, map[string]*bintree{}},
@@ -16,10 +16,16 @@ package manifests
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"reflect"
+	"sort"
 
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/ghodss/yaml"
 	"k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/util/yaml"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Config struct {
@@ -32,42 +38,135 @@ type Config struct {
 	KubeRbacProxyConfig      *KubeRbacProxyConfig      `json:"kubeRbacProxy"`
 	GrafanaConfig            *GrafanaConfig            `json:"grafana"`
 	EtcdConfig               *EtcdConfig               `json:"etcd"`
+	SecretRotation           *SecretRotationConfig     `json:"secretRotation"`
+
+	// Platform holds the optional APIs served by the cluster. It is not
+	// part of the configuration, but detected by the operator.
+	Platform Platform `json:"-"`
+
+	warnings []string
+}
+
+// Platform tells which optional APIs the cluster the monitoring stack is
+// deployed to serves.
+type Platform struct {
+	// Routes is true if OpenShift Routes are served.
+	Routes bool
+	// SecurityContextConstraints is true if OpenShift
+	// SecurityContextConstraints are served.
+	SecurityContextConstraints bool
+}
+
+// OpenShiftPlatform serves all optional APIs. It is the platform of
+// configurations until SetPlatform is called.
+var OpenShiftPlatform = Platform{Routes: true, SecurityContextConstraints: true}
+
+// KubernetesPlatform serves none of the optional APIs.
+var KubernetesPlatform = Platform{}
+
+// ExposeType tells how the web UI and API of a component are exposed
+// outside of the cluster.
+type ExposeType string
+
+const (
+	// ExposeRoute exposes a component through an OpenShift Route.
+	ExposeRoute ExposeType = "Route"
+	// ExposeIngress exposes a component through an Ingress.
+	ExposeIngress ExposeType = "Ingress"
+	// ExposeNodePort exposes a component on a port of every node.
+	ExposeNodePort ExposeType = "NodePort"
+	// ExposeLoadBalancer exposes a component through a load balancer
+	// provisioned by the cloud provider.
+	ExposeLoadBalancer ExposeType = "LoadBalancer"
+	// ExposeNone does not expose a component.
+	ExposeNone ExposeType = "None"
+)
+
+// ExposeConfig tells how the web UI and API of a component are exposed. The
+// external URL of the component is the hostport of the component if set, and
+// otherwise the host it is exposed on, if known.
+type ExposeConfig struct {
+	// Type defaults to Route if Routes are served, and to None otherwise.
+	Type ExposeType `json:"type"`
+	// Annotations are set on the Ingress, or on the Service for the
+	// NodePort and LoadBalancer types, such as to select an ingress
+	// class or an internal load balancer.
+	Annotations map[string]string `json:"annotations"`
+	// TLSSecretName is the Secret holding the certificate the Ingress
+	// terminates TLS with.
+	TLSSecretName string `json:"tlsSecretName"`
+}
+
+// TypeOn returns the type of exposure on platform p. c may be nil.
+func (c *ExposeConfig) TypeOn(p Platform) ExposeType {
+	if c != nil && c.Type != "" {
+		return c.Type
+	}
+	if p.Routes {
+		return ExposeRoute
+	}
+	return ExposeNone
 }
 
 type PrometheusOperatorConfig struct {
-	BaseImage                   string `json:"baseImage"`
-	Tag                         string `json:"-"`
-	PrometheusConfigReloader    string `json:"prometheusConfigReloaderBaseImage"`
-	PrometheusConfigReloaderTag string `json:"-"`
-	ConfigReloaderImage         string `json:"configReloaderBaseImage"`
-	ConfigReloaderTag           string `json:"-"`
+	BaseImage                   string                             `json:"baseImage"`
+	Tag                         string                             `json:"-"`
+	PrometheusConfigReloader    string                             `json:"prometheusConfigReloaderBaseImage"`
+	PrometheusConfigReloaderTag string                             `json:"-"`
+	ConfigReloaderImage         string                             `json:"configReloaderBaseImage"`
+	ConfigReloaderTag           string                             `json:"-"`
+	Tolerations                 []v1.Toleration                    `json:"tolerations"`
+	Affinity                    *v1.Affinity                       `json:"affinity"`
+	TopologySpreadConstraints   []TopologySpreadConstraint         `json:"topologySpreadConstraints"`
+	ContainerResources          map[string]v1.ResourceRequirements `json:"containerResources"`
+	RetryPolicy                 *RetryPolicy                       `json:"retryPolicy"`
 }
 
 type PrometheusK8sConfig struct {
-	Retention           string                    `json:"retention"`
-	BaseImage           string                    `json:"baseImage"`
-	Tag                 string                    `json:"-"`
-	NodeSelector        map[string]string         `json:"nodeSelector"`
-	Resources           *v1.ResourceRequirements  `json:"resources"`
-	ExternalLabels      map[string]string         `json:"externalLabels"`
-	VolumeClaimTemplate *v1.PersistentVolumeClaim `json:"volumeClaimTemplate"`
-	Hostport            string                    `json:"hostport"`
+	Retention                 string                             `json:"retention"`
+	BaseImage                 string                             `json:"baseImage"`
+	Tag                       string                             `json:"-"`
+	NodeSelector              map[string]string                  `json:"nodeSelector"`
+	Tolerations               []v1.Toleration                    `json:"tolerations"`
+	Affinity                  *v1.Affinity                       `json:"affinity"`
+	TopologySpreadConstraints []TopologySpreadConstraint         `json:"topologySpreadConstraints"`
+	Resources                 *v1.ResourceRequirements           `json:"resources"`
+	ContainerResources        map[string]v1.ResourceRequirements `json:"containerResources"`
+	ExternalLabels            map[string]string                  `json:"externalLabels"`
+	RemoteWrite               []RemoteWriteSpec                  `json:"remoteWrite"`
+	Rules                     *RulesConfig                       `json:"rules"`
+	VolumeClaimTemplate       *v1.PersistentVolumeClaim          `json:"volumeClaimTemplate"`
+	Hostport                  string                             `json:"hostport"`
+	Expose                    *ExposeConfig                      `json:"expose"`
+	RetryPolicy               *RetryPolicy                       `json:"retryPolicy"`
 }
 
 type AlertmanagerMainConfig struct {
-	BaseImage           string                    `json:"baseImage"`
-	Tag                 string                    `json:"-"`
-	NodeSelector        map[string]string         `json:"nodeSelector"`
-	Resources           *v1.ResourceRequirements  `json:"resources"`
-	VolumeClaimTemplate *v1.PersistentVolumeClaim `json:"volumeClaimTemplate"`
-	Hostport            string                    `json:"hostport"`
+	BaseImage                 string                             `json:"baseImage"`
+	Tag                       string                             `json:"-"`
+	NodeSelector              map[string]string                  `json:"nodeSelector"`
+	Tolerations               []v1.Toleration                    `json:"tolerations"`
+	Affinity                  *v1.Affinity                       `json:"affinity"`
+	TopologySpreadConstraints []TopologySpreadConstraint         `json:"topologySpreadConstraints"`
+	Resources                 *v1.ResourceRequirements           `json:"resources"`
+	ContainerResources        map[string]v1.ResourceRequirements `json:"containerResources"`
+	VolumeClaimTemplate       *v1.PersistentVolumeClaim          `json:"volumeClaimTemplate"`
+	Hostport                  string                             `json:"hostport"`
+	Expose                    *ExposeConfig                      `json:"expose"`
+	RetryPolicy               *RetryPolicy                       `json:"retryPolicy"`
 }
 
 type GrafanaConfig struct {
-	BaseImage    string            `json:"baseImage"`
-	Tag          string            `json:"-"`
-	NodeSelector map[string]string `json:"nodeSelector"`
-	Hostport     string            `json:"hostport"`
+	BaseImage                 string                             `json:"baseImage"`
+	Tag                       string                             `json:"-"`
+	NodeSelector              map[string]string                  `json:"nodeSelector"`
+	Tolerations               []v1.Toleration                    `json:"tolerations"`
+	Affinity                  *v1.Affinity                       `json:"affinity"`
+	TopologySpreadConstraints []TopologySpreadConstraint         `json:"topologySpreadConstraints"`
+	Hostport                  string                             `json:"hostport"`
+	Expose                    *ExposeConfig                      `json:"expose"`
+	ContainerResources        map[string]v1.ResourceRequirements `json:"containerResources"`
+	RetryPolicy               *RetryPolicy                       `json:"retryPolicy"`
 }
 
 type AuthConfig struct {
@@ -76,14 +175,24 @@ type AuthConfig struct {
 }
 
 type NodeExporterConfig struct {
-	BaseImage string `json:"baseImage"`
-	Tag       string `json:"-"`
+	BaseImage                 string                             `json:"baseImage"`
+	Tag                       string                             `json:"-"`
+	Tolerations               []v1.Toleration                    `json:"tolerations"`
+	Affinity                  *v1.Affinity                       `json:"affinity"`
+	TopologySpreadConstraints []TopologySpreadConstraint         `json:"topologySpreadConstraints"`
+	ContainerResources        map[string]v1.ResourceRequirements `json:"containerResources"`
+	RetryPolicy               *RetryPolicy                       `json:"retryPolicy"`
 }
 
 type KubeStateMetricsConfig struct {
-	BaseImage    string            `json:"baseImage"`
-	Tag          string            `json:"-"`
-	NodeSelector map[string]string `json:"nodeSelector"`
+	BaseImage                 string                             `json:"baseImage"`
+	Tag                       string                             `json:"-"`
+	NodeSelector              map[string]string                  `json:"nodeSelector"`
+	Tolerations               []v1.Toleration                    `json:"tolerations"`
+	Affinity                  *v1.Affinity                       `json:"affinity"`
+	TopologySpreadConstraints []TopologySpreadConstraint         `json:"topologySpreadConstraints"`
+	ContainerResources        map[string]v1.ResourceRequirements `json:"containerResources"`
+	RetryPolicy               *RetryPolicy                       `json:"retryPolicy"`
 }
 
 type KubeRbacProxyConfig struct {
@@ -91,6 +200,232 @@ type KubeRbacProxyConfig struct {
 	Tag       string `json:"-"`
 }
 
+// UnsatisfiableConstraintAction tells how to schedule a pod that does not
+// satisfy a topology spread constraint.
+type UnsatisfiableConstraintAction string
+
+const (
+	// ScheduleAnyway schedules the pod, preferring the topology domains
+	// satisfying the constraint. It is the only supported action.
+	ScheduleAnyway UnsatisfiableConstraintAction = "ScheduleAnyway"
+)
+
+// TopologySpreadConstraint spreads the pods of a component across the
+// domains of a topology, such as zones. The Kubernetes API the operator is
+// built against has no topology spread constraints, so they are only
+// approximated with preferred pod anti-affinity on the topology key: the
+// scheduler prefers the domains not running any of the pods matching the
+// label selector yet, but does not balance the pods once every domain runs
+// one of them, and never leaves a pod pending to satisfy the constraint.
+type TopologySpreadConstraint struct {
+	// MaxSkew must be 1 if set, the only skew pod anti-affinity aims at.
+	MaxSkew int32 `json:"maxSkew"`
+	// TopologyKey is the node label telling the domains apart, such as
+	// failure-domain.beta.kubernetes.io/zone.
+	TopologyKey string `json:"topologyKey"`
+	// WhenUnsatisfiable must be ScheduleAnyway if set. DoNotSchedule is
+	// rejected, as required pod anti-affinity would never run more than
+	// one of the pods per domain.
+	WhenUnsatisfiable UnsatisfiableConstraintAction `json:"whenUnsatisfiable"`
+	// LabelSelector selects the pods to spread, the pods of the component
+	// by default.
+	LabelSelector *metav1.LabelSelector `json:"labelSelector"`
+}
+
+// RemoteWriteSpec configures an endpoint Prometheus sends its samples to.
+// Credentials are read from Secrets in the namespace of the monitoring
+// stack, which are watched so that Prometheus is restarted when they change.
+type RemoteWriteSpec struct {
+	URL string `json:"url"`
+	// RemoteTimeout of the requests to the endpoint, such as 30s.
+	RemoteTimeout       string                `json:"remoteTimeout"`
+	WriteRelabelConfigs []monv1.RelabelConfig `json:"writeRelabelConfigs"`
+	QueueConfig         *monv1.QueueConfig    `json:"queueConfig"`
+	BasicAuth           *monv1.BasicAuth      `json:"basicAuth"`
+	BearerToken         *v1.SecretKeySelector `json:"bearerToken"`
+	TLSConfig           *RemoteWriteTLSConfig `json:"tlsConfig"`
+	ProxyURL            string                `json:"proxyUrl"`
+}
+
+// RemoteWriteTLSConfig configures the TLS connections to a remote write
+// endpoint. The CA, client certificate and key are read from Secrets.
+type RemoteWriteTLSConfig struct {
+	CA                 *v1.SecretKeySelector `json:"ca"`
+	Cert               *v1.SecretKeySelector `json:"cert"`
+	Key                *v1.SecretKeySelector `json:"key"`
+	ServerName         string                `json:"serverName"`
+	InsecureSkipVerify bool                  `json:"insecureSkipVerify"`
+}
+
+// secretKeySelectors returns the references to the Secrets of the remote
+// write endpoint.
+func (s *RemoteWriteSpec) secretKeySelectors() []*v1.SecretKeySelector {
+	res := []*v1.SecretKeySelector{}
+	if s.BasicAuth != nil {
+		res = append(res, &s.BasicAuth.Username, &s.BasicAuth.Password)
+	}
+	return append(res, s.mountedSecrets()...)
+}
+
+// mountedSecrets returns the references to the Secrets of the remote write
+// endpoint that are read by Prometheus from files, unlike the basic auth
+// credentials, which the Prometheus Operator writes to its configuration.
+func (s *RemoteWriteSpec) mountedSecrets() []*v1.SecretKeySelector {
+	res := []*v1.SecretKeySelector{}
+	if s.BearerToken != nil {
+		res = append(res, s.BearerToken)
+	}
+	if s.TLSConfig != nil {
+		for _, sel := range []*v1.SecretKeySelector{s.TLSConfig.CA, s.TLSConfig.Cert, s.TLSConfig.Key} {
+			if sel != nil {
+				res = append(res, sel)
+			}
+		}
+	}
+	return res
+}
+
+// RemoteWriteSecrets returns the sorted names of the Secrets referenced by
+// the remote write endpoints of Prometheus.
+func (c *Config) RemoteWriteSecrets() []string {
+	if c.PrometheusK8sConfig == nil {
+		return nil
+	}
+
+	names := map[string]bool{}
+	for i := range c.PrometheusK8sConfig.RemoteWrite {
+		for _, sel := range c.PrometheusK8sConfig.RemoteWrite[i].secretKeySelectors() {
+			names[sel.Name] = true
+		}
+	}
+
+	res := make([]string, 0, len(names))
+	for n := range names {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// RulesConfig customizes the alerting and recording rules evaluated by
+// Prometheus.
+type RulesConfig struct {
+	// Groups are evaluated in addition to the default rule groups. Their
+	// names are prefixed with UserRuleGroupPrefix, so that they never
+	// collide with the default groups.
+	Groups []monv1.RuleGroup `json:"groups"`
+	// DisabledAlerts are the names of the default alerts that are not
+	// evaluated.
+	DisabledAlerts []string `json:"disabledAlerts"`
+	// AlertOverrides change default alerts.
+	AlertOverrides []AlertOverride `json:"alertOverrides"`
+}
+
+// AlertOverride changes the default alerts of a name. Some alerts are
+// defined once per severity, MatchLabels tells them apart.
+type AlertOverride struct {
+	Alert string `json:"alert"`
+	// MatchLabels restricts the override to the alerts having these
+	// labels.
+	MatchLabels map[string]string `json:"matchLabels"`
+	// For replaces the duration the alert condition has to hold before
+	// the alert fires, if set.
+	For string `json:"for"`
+	// Labels and Annotations are set on the alerts. Empty values remove
+	// them.
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// matches returns true if the override applies to the rule.
+func (o *AlertOverride) matches(r monv1.Rule) bool {
+	if r.Alert == "" || r.Alert != o.Alert {
+		return false
+	}
+	for k, v := range o.MatchLabels {
+		if r.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// apply returns a copy of the rule changed by the override.
+func (o *AlertOverride) apply(r monv1.Rule) monv1.Rule {
+	res := *r.DeepCopy()
+	if o.For != "" {
+		res.For = o.For
+	}
+	res.Labels = mergeStringMaps(res.Labels, o.Labels)
+	res.Annotations = mergeStringMaps(res.Annotations, o.Annotations)
+	return res
+}
+
+// mergeStringMaps returns m with the values of overrides set, or removed if
+// empty.
+func mergeStringMaps(m, overrides map[string]string) map[string]string {
+	if len(overrides) == 0 {
+		return m
+	}
+	if m == nil {
+		m = map[string]string{}
+	}
+	for k, v := range overrides {
+		if v == "" {
+			delete(m, k)
+			continue
+		}
+		m[k] = v
+	}
+	return m
+}
+
+// RetryPolicy controls how long the operator waits for the objects of a
+// component to be ready, and how often it retries reconciling the component
+// when it fails. Unset fields default to the flags of the operator.
+type RetryPolicy struct {
+	RolloutTimeout  *metav1.Duration `json:"rolloutTimeout"`
+	PollInterval    *metav1.Duration `json:"pollInterval"`
+	MaxRetries      *int             `json:"maxRetries"`
+	RetryBackoff    *metav1.Duration `json:"retryBackoff"`
+	MaxRetryBackoff *metav1.Duration `json:"maxRetryBackoff"`
+}
+
+// WithDefaults returns a copy of the policy whose unset fields are set to
+// the ones of defaults. p may be nil.
+func (p *RetryPolicy) WithDefaults(defaults RetryPolicy) RetryPolicy {
+	if p == nil {
+		return defaults
+	}
+
+	res := *p
+	if res.RolloutTimeout == nil {
+		res.RolloutTimeout = defaults.RolloutTimeout
+	}
+	if res.PollInterval == nil {
+		res.PollInterval = defaults.PollInterval
+	}
+	if res.MaxRetries == nil {
+		res.MaxRetries = defaults.MaxRetries
+	}
+	if res.RetryBackoff == nil {
+		res.RetryBackoff = defaults.RetryBackoff
+	}
+	if res.MaxRetryBackoff == nil {
+		res.MaxRetryBackoff = defaults.MaxRetryBackoff
+	}
+	return res
+}
+
+// SecretRotationConfig controls the rotation of the secrets generated by the
+// operator, the session secrets of the authentication proxies and the
+// password Grafana authenticates to Prometheus with.
+type SecretRotationConfig struct {
+	// Period after which generated secrets are rotated. Secrets are only
+	// rotated on demand if unset.
+	Period *metav1.Duration `json:"period"`
+}
+
 type EtcdConfig struct {
 	Enabled   *bool          `json:"enabled"`
 	Targets   EtcdTargets    `json:"targets,omitempty"`
@@ -106,21 +441,55 @@ type EtcdTLSConfig struct {
 	ServerName string `json:"serverName"`
 }
 
+// NewConfig decodes and validates a configuration. Unknown fields are
+// rejected, deprecated fields are ignored with a warning, and the errors of
+// invalid configurations carry the paths of the offending fields.
 func NewConfig(content io.Reader) (*Config, error) {
-	c := Config{}
+	b, err := ioutil.ReadAll(content)
+	if err != nil {
+		return nil, err
+	}
+
+	j, err := yaml.YAMLToJSON(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+	err = json.Unmarshal(j, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if errs := validateFields(nil, raw, reflect.TypeOf(Config{})); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
 
-	err := yaml.NewYAMLOrJSONDecoder(content, 100).Decode(&c)
+	c := Config{}
+	err = json.Unmarshal(j, &c)
 	if err != nil {
 		return nil, err
 	}
 
+	if errs := c.Validate(); len(errs) > 0 {
+		return nil, errs.ToAggregate()
+	}
+
 	res := &c
+	res.warnings = deprecationWarnings(raw)
 	res.applyDefaults()
 
 	return res, nil
 }
 
+// Warnings returns the warnings about the configuration, such as the
+// deprecated fields it sets.
+func (c *Config) Warnings() []string {
+	return c.warnings
+}
+
 func (c *Config) applyDefaults() {
+	c.Platform = OpenShiftPlatform
 	if c.PrometheusOperatorConfig == nil {
 		c.PrometheusOperatorConfig = &PrometheusOperatorConfig{}
 	}
@@ -199,6 +568,11 @@ func (c *Config) SetTagOverrides(tagOverrides map[string]string) {
 	c.KubeRbacProxyConfig.Tag, _ = tagOverrides["kube-rbac-proxy"]
 }
 
+// SetPlatform sets the platform the configuration is applied to.
+func (c *Config) SetPlatform(p Platform) {
+	c.Platform = p
+}
+
 func NewConfigFromString(content string) (*Config, error) {
 	if content == "" {
 		return NewDefaultConfig(), nil
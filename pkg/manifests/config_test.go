@@ -16,6 +16,8 @@ package manifests
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -31,9 +33,445 @@ func TestConfigParsing(t *testing.T) {
 	}
 }
 
+func TestUserGuideConfigsParsing(t *testing.T) {
+	paths, err := filepath.Glob("../../examples/user-guides/configuring-cluster-monitoring/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewConfig(f)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		}
+	}
+}
+
+func TestDeprecatedFieldsAreIgnored(t *testing.T) {
+	c, err := NewConfigFromString(`prometheusK8s:
+  externalUrl: https://prometheus.example.com
+alertmanagerMain:
+  externalUrl: https://alertmanager.example.com
+kubeStateMetrics:
+  addonResizerBaseImage: quay.io/coreos/addon-resizer
+ingress:
+  baseAddress: example.com
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"alertmanagerMain.externalUrl",
+		"ingress",
+		"kubeStateMetrics.addonResizerBaseImage",
+		"prometheusK8s.externalUrl",
+	}
+	warnings := c.Warnings()
+	if len(warnings) != len(expected) {
+		t.Fatalf("expected %d warnings, got %v", len(expected), warnings)
+	}
+	for i, path := range expected {
+		if !strings.HasPrefix(warnings[i], path+" is deprecated") {
+			t.Errorf("expected warning about %s, got %q", path, warnings[i])
+		}
+	}
+
+	c, err = NewConfigFromString(`prometheusK8s:
+  retention: 24h
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Warnings()) != 0 {
+		t.Errorf("expected no warnings, got %v", c.Warnings())
+	}
+}
+
 func TestEmptyConfigIsValid(t *testing.T) {
 	_, err := NewConfigFromString("")
 	if err != nil {
 		t.Fatal(err)
 	}
 }
+
+func TestConfigValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		config string
+		errs   []string
+	}{
+		{
+			name: "valid",
+			config: `prometheusK8s:
+  retention: 24h
+  baseImage: registry.example.com:5000/prometheus/prometheus
+  resources:
+    requests:
+      memory: 1Gi
+    limits:
+      memory: 2Gi
+  retryPolicy:
+    rolloutTimeout: 15m
+    pollInterval: 30s
+    maxRetries: 3
+etcd:
+  targets:
+    ips:
+    - 10.0.0.1
+`,
+		}, {
+			name: "unknown fields",
+			config: `prometheusK8s:
+  retension: 24h
+alertmanager:
+  baseImage: quay.io/prometheus/alertmanager
+`,
+			errs: []string{"alertmanager", "prometheusK8s.retension"},
+		}, {
+			name: "invalid quantities",
+			config: `alertmanagerMain:
+  resources:
+    limits:
+      memory: lots
+`,
+			errs: []string{"alertmanagerMain.resources.limits[memory]"},
+		}, {
+			name: "invalid retry policy",
+			config: `grafana:
+  retryPolicy:
+    rolloutTimeout: -5m
+nodeExporter:
+  retryPolicy:
+    pollInterval: 0s
+    maxRetries: -1
+`,
+			errs: []string{
+				"grafana.retryPolicy.rolloutTimeout",
+				"nodeExporter.retryPolicy.pollInterval",
+				"nodeExporter.retryPolicy.maxRetries",
+			},
+		}, {
+			name: "invalid exposure",
+			config: `prometheusK8s:
+  expose:
+    type: Gateway
+alertmanagerMain:
+  expose:
+    type: LoadBalancer
+    tlsSecretName: alertmanager-tls
+grafana:
+  expose:
+    type: Ingress
+    annotations:
+      "not a key": "true"
+`,
+			errs: []string{
+				"prometheusK8s.expose.type",
+				"alertmanagerMain.expose.tlsSecretName",
+				"grafana.expose.annotations[not a key]",
+			},
+		}, {
+			name: "invalid values",
+			config: `prometheusK8s:
+  retention: 24 hours
+  baseImage: quay.io/prometheus/prometheus:v2.3.2
+  resources:
+    requests:
+      cpu: 2
+    limits:
+      cpu: 1
+etcd:
+  targets:
+    ips:
+    - 10.0.0.1
+    - 10.0.0.256
+`,
+			errs: []string{
+				"prometheusK8s.retention",
+				"prometheusK8s.baseImage",
+				"prometheusK8s.resources.requests[cpu]",
+				"etcd.targets.ips[1]",
+			},
+		}, {
+			name: "valid scheduling",
+			config: `prometheusK8s:
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+    effect: NoSchedule
+  affinity:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+        - matchExpressions:
+          - key: node-role.kubernetes.io/infra
+            operator: Exists
+  topologySpreadConstraints:
+  - maxSkew: 1
+    topologyKey: failure-domain.beta.kubernetes.io/zone
+    whenUnsatisfiable: ScheduleAnyway
+nodeExporter:
+  tolerations:
+  - operator: Exists
+`,
+		}, {
+			name: "invalid scheduling",
+			config: `alertmanagerMain:
+  tolerations:
+  - key: dedicated
+    operator: Exists
+    value: monitoring
+    effect: NoSchedulePlease
+  topologySpreadConstraints:
+  - maxSkew: 2
+    whenUnsatisfiable: Never
+prometheusOperator:
+  topologySpreadConstraints:
+  - topologyKey: "not a key"
+    whenUnsatisfiable: DoNotSchedule
+    labelSelector:
+      matchExpressions:
+      - key: app
+        operator: Near
+`,
+			errs: []string{
+				"alertmanagerMain.tolerations[0].value",
+				"alertmanagerMain.tolerations[0].effect",
+				"alertmanagerMain.topologySpreadConstraints[0].maxSkew",
+				"alertmanagerMain.topologySpreadConstraints[0].topologyKey",
+				"alertmanagerMain.topologySpreadConstraints[0].whenUnsatisfiable",
+				"prometheusOperator.topologySpreadConstraints[0].topologyKey",
+				"prometheusOperator.topologySpreadConstraints[0].whenUnsatisfiable",
+				"prometheusOperator.topologySpreadConstraints[0].labelSelector",
+			},
+		}, {
+			name: "invalid container resources",
+			config: `prometheusK8s:
+  resources:
+    limits:
+      memory: 2Gi
+  containerResources:
+    prometheus:
+      limits:
+        memory: 2Gi
+kubeStateMetrics:
+  containerResources:
+    kube-state-metrics:
+      requests:
+        memory: 2Gi
+      limits:
+        memory: 1Gi
+    config-reloader:
+      limits:
+        memory: 10Mi
+`,
+			errs: []string{
+				"prometheusK8s.containerResources[prometheus]",
+				"kubeStateMetrics.containerResources[kube-state-metrics].requests[memory]",
+				"kubeStateMetrics.containerResources[config-reloader]",
+			},
+		}, {
+			name: "valid remote write",
+			config: `prometheusK8s:
+  remoteWrite:
+  - url: https://remote.example.com/api/v1/write
+    remoteTimeout: 30s
+    writeRelabelConfigs:
+    - sourceLabels: [__name__]
+      regex: "up|node_.*"
+      action: keep
+    queueConfig:
+      maxShards: 10
+      batchSendDeadline: 5s
+    basicAuth:
+      username:
+        name: remote-write-auth
+        key: user
+      password:
+        name: remote-write-auth
+        key: password
+    tlsConfig:
+      ca:
+        name: remote-write-tls
+        key: ca.crt
+      cert:
+        name: remote-write-tls
+        key: tls.crt
+      key:
+        name: remote-write-tls
+        key: tls.key
+`,
+		}, {
+			name: "invalid remote write",
+			config: `prometheusK8s:
+  remoteWrite:
+  - remoteTimeout: 30 seconds
+    writeRelabelConfigs:
+    - action: rename
+      regex: "(unclosed"
+    - action: hashmod
+    queueConfig:
+      maxShards: -1
+      maxBackoff: 1 minute
+    basicAuth:
+      username:
+        name: remote-write-auth
+    bearerToken:
+      name: Remote_Write
+      key: token
+    tlsConfig:
+      cert:
+        name: remote-write-tls
+        key: tls.crt
+  - url: remote.example.com/write
+    proxyUrl: socks5://proxy
+`,
+			errs: []string{
+				"prometheusK8s.remoteWrite[0].url",
+				"prometheusK8s.remoteWrite[0].remoteTimeout",
+				"prometheusK8s.remoteWrite[0].writeRelabelConfigs[0].action",
+				"prometheusK8s.remoteWrite[0].writeRelabelConfigs[0].regex",
+				"prometheusK8s.remoteWrite[0].writeRelabelConfigs[1].targetLabel",
+				"prometheusK8s.remoteWrite[0].writeRelabelConfigs[1].modulus",
+				"prometheusK8s.remoteWrite[0].queueConfig.maxShards",
+				"prometheusK8s.remoteWrite[0].queueConfig.maxBackoff",
+				"prometheusK8s.remoteWrite[0].basicAuth.username.key",
+				"prometheusK8s.remoteWrite[0].basicAuth.password.name",
+				"prometheusK8s.remoteWrite[0].bearerToken.name",
+				"prometheusK8s.remoteWrite[0].bearerToken",
+				"prometheusK8s.remoteWrite[0].tlsConfig.key",
+				"prometheusK8s.remoteWrite[1].url",
+				"prometheusK8s.remoteWrite[1].proxyUrl",
+			},
+		}, {
+			name: "valid rules",
+			config: `prometheusK8s:
+  rules:
+    groups:
+    - name: platform
+      interval: 1m
+      rules:
+      - record: job:up:sum
+        expr: sum(up) by (job)
+      - alert: PlatformDown
+        expr: job:up:sum{job="platform"} == 0
+        for: 5m
+        labels:
+          severity: critical
+        annotations:
+          message: The platform is down.
+`,
+		}, {
+			name: "invalid rules",
+			config: `prometheusK8s:
+  rules:
+    groups:
+    - name: platform
+      interval: 1 minute
+      rules:
+      - expr: up
+      - record: job-up
+        alert: JobUp
+        expr: up
+      - record: job:up
+        expr: " "
+        for: 5m
+        annotations:
+          message: up
+      - alert: PlatformDown
+        expr: up == 0
+        for: five minutes
+        labels:
+          bad-label: x
+    - name: platform
+      rules: []
+    - rules: []
+`,
+			errs: []string{
+				"prometheusK8s.rules.groups[0].interval",
+				"prometheusK8s.rules.groups[0].rules[0]",
+				"prometheusK8s.rules.groups[0].rules[1].alert",
+				"prometheusK8s.rules.groups[0].rules[2].expr",
+				"prometheusK8s.rules.groups[0].rules[2].for",
+				"prometheusK8s.rules.groups[0].rules[2].annotations",
+				"prometheusK8s.rules.groups[0].rules[3].for",
+				"prometheusK8s.rules.groups[0].rules[3].labels[bad-label]",
+				"prometheusK8s.rules.groups[1].name",
+				"prometheusK8s.rules.groups[2].name",
+			},
+		}, {
+			name: "valid alert overrides",
+			config: `prometheusK8s:
+  rules:
+    disabledAlerts:
+    - KubePodCrashLooping
+    alertOverrides:
+    - alert: NodeDiskRunningFull
+      matchLabels:
+        severity: critical
+      for: 30m
+      labels:
+        severity: warning
+`,
+		}, {
+			name: "invalid alert overrides",
+			config: `prometheusK8s:
+  rules:
+    disabledAlerts:
+    - KubePodCrashLooping
+    - RenamedAlert
+    alertOverrides:
+    - alert: RenamedAlert
+      labels:
+        severity: info
+    - alert: NodeDiskRunningFull
+      matchLabels:
+        severity: info
+    - alert: KubePodCrashLooping
+      for: 1 hour
+    - labels:
+        bad-label: x
+`,
+			errs: []string{
+				"prometheusK8s.rules.disabledAlerts[1]",
+				"prometheusK8s.rules.alertOverrides[0].alert",
+				"prometheusK8s.rules.alertOverrides[1].matchLabels",
+				"prometheusK8s.rules.alertOverrides[2].alert",
+				"prometheusK8s.rules.alertOverrides[2].for",
+				"prometheusK8s.rules.alertOverrides[3].alert",
+				"prometheusK8s.rules.alertOverrides[3].labels[bad-label]",
+			},
+		}, {
+			name: "rotation period too short",
+			config: `secretRotation:
+  period: 10m
+`,
+			errs: []string{"secretRotation.period"},
+		},
+	}
+
+	for _, tc := range cases {
+		_, err := NewConfigFromString(tc.config)
+		if len(tc.errs) == 0 {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", tc.name, err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%s: expected an error, got none", tc.name)
+			continue
+		}
+		for _, path := range tc.errs {
+			if !strings.Contains(err.Error(), path+":") {
+				t.Errorf("%s: expected error for %s, got %v", tc.name, path, err)
+			}
+		}
+	}
+}
@@ -17,21 +17,29 @@ package manifests
 import (
 	"bytes"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"net/url"
+	"path"
+	"sort"
 	"strings"
 
 	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
 	routev1 "github.com/openshift/api/route/v1"
 	securityv1 "github.com/openshift/api/security/v1"
+	"github.com/pkg/errors"
 	appsv1 "k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
 	"k8s.io/api/extensions/v1beta1"
 	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
@@ -44,6 +52,7 @@ var (
 	AlertmanagerClusterRoleBinding = "assets/alertmanager/cluster-role-binding.yaml"
 	AlertmanagerClusterRole        = "assets/alertmanager/cluster-role.yaml"
 	AlertmanagerRoute              = "assets/alertmanager/route.yaml"
+	AlertmanagerIngress            = "assets/alertmanager/ingress.yaml"
 	AlertmanagerServiceMonitor     = "assets/alertmanager/service-monitor.yaml"
 
 	KubeStateMetricsClusterRoleBinding = "assets/kube-state-metrics/cluster-role-binding.yaml"
@@ -81,6 +90,7 @@ var (
 	PrometheusK8sService                       = "assets/prometheus-k8s/service.yaml"
 	PrometheusK8sProxySecret                   = "assets/prometheus-k8s/proxy-secret.yaml"
 	PrometheusK8sRoute                         = "assets/prometheus-k8s/route.yaml"
+	PrometheusK8sIngress                       = "assets/prometheus-k8s/ingress.yaml"
 	PrometheusK8sHtpasswd                      = "assets/prometheus-k8s/htpasswd-secret.yaml"
 	PrometheusK8sEtcdService                   = "assets/prometheus-k8s/service-etcd.yaml"
 	PrometheusK8sEtcdEndpoints                 = "assets/prometheus-k8s/endpoints-etcd.yaml"
@@ -105,8 +115,12 @@ var (
 	GrafanaDeployment           = "assets/grafana/deployment.yaml"
 	GrafanaProxySecret          = "assets/grafana/proxy-secret.yaml"
 	GrafanaRoute                = "assets/grafana/route.yaml"
+	GrafanaIngress              = "assets/grafana/ingress.yaml"
 	GrafanaServiceAccount       = "assets/grafana/service-account.yaml"
 	GrafanaService              = "assets/grafana/service.yaml"
+
+	ClusterMonitoringOperatorService        = "assets/cluster-monitoring-operator/service.yaml"
+	ClusterMonitoringOperatorServiceMonitor = "assets/cluster-monitoring-operator/service-monitor.yaml"
 )
 
 var (
@@ -119,8 +133,113 @@ var (
 	AuthProxyRedirectURLFlag  = "-redirect-url="
 )
 
+const (
+	// ManagedByLabel is set on every object produced by the Factory, so
+	// that objects managed by the operator can be told apart from others.
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedByValue = "cluster-monitoring-operator"
+
+	// ComponentLabel holds the component an object produced by the Factory
+	// belongs to, which is the directory of the asset it was read from.
+	ComponentLabel = "monitoring.openshift.io/component"
+
+	// RotateAnnotation requests the rotation of a generated secret when
+	// set to "true" on it.
+	RotateAnnotation = "monitoring.openshift.io/rotate"
+	// RotatedAtAnnotation holds the time a generated secret was last
+	// rotated at. Secrets that were never rotated are as old as they were
+	// created.
+	RotatedAtAnnotation = "monitoring.openshift.io/rotated-at"
+	// RemoteWriteSecretsHashAnnotation holds the hash of the Secrets
+	// referenced by the remote write endpoints of Prometheus on its pods,
+	// so that they are restarted with the changed credentials.
+	RemoteWriteSecretsHashAnnotation = "monitoring.openshift.io/remote-write-secrets-hash"
+
+	// UserRuleGroupPrefix prefixes the names of the rule groups of the
+	// configuration. Default rule groups never contain a slash.
+	UserRuleGroupPrefix = "user/"
+)
+
+var monitoringGroupVersion = schema.GroupVersion{Group: monv1.Group, Version: monv1.Version}
+
+// ManagedKinds are the kinds of the objects produced by the Factory, whose
+// objects that are not desired anymore are pruned.
+var ManagedKinds = []schema.GroupVersionKind{
+	v1.SchemeGroupVersion.WithKind("Service"),
+	v1.SchemeGroupVersion.WithKind("Endpoints"),
+	v1.SchemeGroupVersion.WithKind("Secret"),
+	v1.SchemeGroupVersion.WithKind("ConfigMap"),
+	v1.SchemeGroupVersion.WithKind("ServiceAccount"),
+	appsv1.SchemeGroupVersion.WithKind("Deployment"),
+	appsv1.SchemeGroupVersion.WithKind("DaemonSet"),
+	rbacv1beta1.SchemeGroupVersion.WithKind("Role"),
+	rbacv1beta1.SchemeGroupVersion.WithKind("RoleBinding"),
+	rbacv1beta1.SchemeGroupVersion.WithKind("ClusterRole"),
+	rbacv1beta1.SchemeGroupVersion.WithKind("ClusterRoleBinding"),
+	monitoringGroupVersion.WithKind(monv1.PrometheusesKind),
+	monitoringGroupVersion.WithKind(monv1.AlertmanagersKind),
+	monitoringGroupVersion.WithKind(monv1.ServiceMonitorsKind),
+	monitoringGroupVersion.WithKind(monv1.PrometheusRuleKind),
+	routev1.SchemeGroupVersion.WithKind("Route"),
+	securityv1.SchemeGroupVersion.WithKind("SecurityContextConstraints"),
+	v1beta1.SchemeGroupVersion.WithKind("Ingress"),
+}
+
+const (
+	ComponentAlertmanager              = "alertmanager"
+	ComponentClusterMonitoringOperator = "cluster-monitoring-operator"
+	ComponentGrafana                   = "grafana"
+	ComponentKubeStateMetrics          = "kube-state-metrics"
+	ComponentNodeExporter              = "node-exporter"
+	ComponentPrometheusK8s             = "prometheus-k8s"
+	ComponentPrometheusOperator        = "prometheus-operator"
+)
+
+// The names of the containers of each component whose resources can be
+// configured. The main containers of Prometheus and Alertmanager are added by
+// the Prometheus Operator, with the resources of their spec.
+var (
+	prometheusOperatorContainers = []string{"prometheus-operator"}
+	prometheusK8sContainers      = []string{"prometheus", "prometheus-proxy"}
+	alertmanagerMainContainers   = []string{"alertmanager", "alertmanager-proxy"}
+	grafanaContainers            = []string{"grafana", "grafana-proxy"}
+	nodeExporterContainers       = []string{"node-exporter", "kube-rbac-proxy"}
+	kubeStateMetricsContainers   = []string{"kube-rbac-proxy-main", "kube-rbac-proxy-self", "kube-state-metrics"}
+)
+
+// assetReader reads an asset and remembers the component it belongs to.
+type assetReader struct {
+	*bytes.Reader
+	component string
+}
+
 func MustAssetReader(asset string) io.Reader {
-	return bytes.NewReader(MustAsset(asset))
+	component := ""
+	if parts := strings.Split(asset, "/"); len(parts) > 2 {
+		component = parts[1]
+	}
+
+	return &assetReader{
+		Reader:    bytes.NewReader(MustAsset(asset)),
+		component: component,
+	}
+}
+
+// setManagedLabels marks an object read from manifest as managed by the
+// operator and, if manifest is an asset, as belonging to the asset's
+// component.
+func setManagedLabels(o metav1.Object, manifest io.Reader) {
+	l := o.GetLabels()
+	if l == nil {
+		l = map[string]string{}
+	}
+
+	l[ManagedByLabel] = ManagedByValue
+	if r, ok := manifest.(*assetReader); ok && r.component != "" {
+		l[ComponentLabel] = r.component
+	}
+
+	o.SetLabels(l)
 }
 
 type Factory struct {
@@ -135,10 +254,230 @@ func NewFactory(namespace string, c *Config) *Factory {
 	}
 }
 
+// Platform returns the platform the objects are produced for.
+func (f *Factory) Platform() Platform {
+	return f.config.Platform
+}
+
+// exposure returns the expose configuration and hostport of a component
+// exposed outside of the cluster.
+func (f *Factory) exposure(component string) (*ExposeConfig, string, error) {
+	switch component {
+	case ComponentPrometheusK8s:
+		return f.config.PrometheusK8sConfig.Expose, f.config.PrometheusK8sConfig.Hostport, nil
+	case ComponentAlertmanager:
+		return f.config.AlertmanagerMainConfig.Expose, f.config.AlertmanagerMainConfig.Hostport, nil
+	case ComponentGrafana:
+		return f.config.GrafanaConfig.Expose, f.config.GrafanaConfig.Hostport, nil
+	}
+	return nil, "", fmt.Errorf("component %s is not exposed", component)
+}
+
+// Exposure returns the object exposing a component outside of the cluster:
+// a Route, an Ingress or, for the NodePort and LoadBalancer types, the
+// Service of the component. nil is returned if the component is not
+// exposed. An error is returned if the component is exposed through a Route
+// but Routes are not served.
+func (f *Factory) Exposure(component string) (runtime.Object, error) {
+	e, _, err := f.exposure(component)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := e.TypeOn(f.config.Platform); t {
+	case ExposeRoute:
+		if !f.config.Platform.Routes {
+			return nil, fmt.Errorf("%s cannot be exposed through a Route, as Routes are not served by the cluster", component)
+		}
+		switch component {
+		case ComponentPrometheusK8s:
+			return f.PrometheusK8sRoute()
+		case ComponentAlertmanager:
+			return f.AlertmanagerRoute()
+		default:
+			return f.GrafanaRoute()
+		}
+	case ExposeIngress:
+		switch component {
+		case ComponentPrometheusK8s:
+			return f.PrometheusK8sIngress()
+		case ComponentAlertmanager:
+			return f.AlertmanagerIngress()
+		default:
+			return f.GrafanaIngress()
+		}
+	case ExposeNodePort, ExposeLoadBalancer:
+		switch component {
+		case ComponentPrometheusK8s:
+			return f.PrometheusK8sService()
+		case ComponentAlertmanager:
+			return f.AlertmanagerService()
+		default:
+			return f.GrafanaService()
+		}
+	case ExposeNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown expose type %q", t)
+	}
+}
+
+// exposeIngress sets the host, TLS and annotations of the Ingress of a
+// component.
+func (f *Factory) exposeIngress(i *v1beta1.Ingress, component string) error {
+	e, hostport, err := f.exposure(component)
+	if err != nil {
+		return err
+	}
+
+	host := ""
+	if hostport != "" {
+		if host, err = hostFromBaseAddress(hostport); err != nil {
+			return err
+		}
+		i.Spec.Rules[0].Host = host
+	}
+
+	if e != nil {
+		if e.TLSSecretName != "" {
+			tls := v1beta1.IngressTLS{SecretName: e.TLSSecretName}
+			if host != "" {
+				tls.Hosts = []string{host}
+			}
+			i.Spec.TLS = []v1beta1.IngressTLS{tls}
+		}
+		setAnnotations(i, e.Annotations)
+	}
+	i.Namespace = f.namespace
+
+	return nil
+}
+
+// exposeService sets the type and annotations of the Service of a component
+// exposed through it.
+func (f *Factory) exposeService(s *v1.Service, component string) error {
+	e, _, err := f.exposure(component)
+	if err != nil {
+		return err
+	}
+
+	switch e.TypeOn(f.config.Platform) {
+	case ExposeNodePort:
+		s.Spec.Type = v1.ServiceTypeNodePort
+	case ExposeLoadBalancer:
+		s.Spec.Type = v1.ServiceTypeLoadBalancer
+	default:
+		return nil
+	}
+	setAnnotations(s, e.Annotations)
+
+	return nil
+}
+
+func setAnnotations(o metav1.Object, annotations map[string]string) {
+	if len(annotations) == 0 {
+		return
+	}
+
+	a := o.GetAnnotations()
+	if a == nil {
+		a = map[string]string{}
+	}
+	for k, v := range annotations {
+		a[k] = v
+	}
+	o.SetAnnotations(a)
+}
+
+// setContainerResources replaces the resources of the containers that have
+// configured resources.
+func setContainerResources(containers []v1.Container, resources map[string]v1.ResourceRequirements) {
+	for i := range containers {
+		if r, ok := resources[containers[i].Name]; ok {
+			containers[i].Resources = *r.DeepCopy()
+		}
+	}
+}
+
+// prometheusSecretsDir is the directory the Prometheus Operator mounts the
+// secrets of a Prometheus resource in.
+const prometheusSecretsDir = "/etc/prometheus/secrets"
+
+// hostnameTopologyKey is the node label telling nodes apart.
+const hostnameTopologyKey = "kubernetes.io/hostname"
+
+// podAffinity returns the affinity of the pods of a component with the given
+// labels: the configured affinity or, if none is configured and the
+// component is replicated, a soft pod anti-affinity spreading its pods
+// across nodes. The pod anti-affinity enforcing the topology spread
+// constraints is added to it.
+func podAffinity(configured *v1.Affinity, constraints []TopologySpreadConstraint, podLabels map[string]string, replicated bool) *v1.Affinity {
+	var a *v1.Affinity
+	switch {
+	case configured != nil:
+		a = configured.DeepCopy()
+	case replicated:
+		a = &v1.Affinity{PodAntiAffinity: &v1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v1.WeightedPodAffinityTerm{{
+				Weight: 100,
+				PodAffinityTerm: v1.PodAffinityTerm{
+					LabelSelector: podSelector(podLabels),
+					TopologyKey:   hostnameTopologyKey,
+				},
+			}},
+		}}
+	}
+	if len(constraints) == 0 {
+		return a
+	}
+
+	if a == nil {
+		a = &v1.Affinity{}
+	}
+	if a.PodAntiAffinity == nil {
+		a.PodAntiAffinity = &v1.PodAntiAffinity{}
+	}
+	for _, c := range constraints {
+		term := v1.PodAffinityTerm{
+			LabelSelector: c.LabelSelector.DeepCopy(),
+			TopologyKey:   c.TopologyKey,
+		}
+		if term.LabelSelector == nil {
+			term.LabelSelector = podSelector(podLabels)
+		}
+		a.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(a.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution, v1.WeightedPodAffinityTerm{
+			Weight:          100,
+			PodAffinityTerm: term,
+		})
+	}
+	return a
+}
+
+func podSelector(podLabels map[string]string) *metav1.LabelSelector {
+	l := make(map[string]string, len(podLabels))
+	for k, v := range podLabels {
+		l[k] = v
+	}
+	return &metav1.LabelSelector{MatchLabels: l}
+}
+
+// setPodScheduling adds the tolerations to the ones of the pod template of a
+// workload, and sets its affinity as returned by podAffinity.
+func setPodScheduling(t *v1.PodTemplateSpec, tolerations []v1.Toleration, affinity *v1.Affinity, constraints []TopologySpreadConstraint, replicated bool) {
+	t.Spec.Tolerations = append(t.Spec.Tolerations, tolerations...)
+	t.Spec.Affinity = podAffinity(affinity, constraints, t.Labels, replicated)
+}
+
+// PrometheusExternalURL returns the URL Prometheus is reachable at, which is
+// its hostport if set, and otherwise host. nil is returned if both are
+// empty.
 func (f *Factory) PrometheusExternalURL(host string) *url.URL {
 	if f.config.PrometheusK8sConfig.Hostport != "" {
 		host = f.config.PrometheusK8sConfig.Hostport
 	}
+	if host == "" {
+		return nil
+	}
 
 	return &url.URL{
 		Scheme: "https",
@@ -147,10 +486,16 @@ func (f *Factory) PrometheusExternalURL(host string) *url.URL {
 	}
 }
 
+// AlertmanagerExternalURL returns the URL Alertmanager is reachable at,
+// which is its hostport if set, and otherwise host. nil is returned if both
+// are empty.
 func (f *Factory) AlertmanagerExternalURL(host string) *url.URL {
 	if f.config.AlertmanagerMainConfig.Hostport != "" {
 		host = f.config.AlertmanagerMainConfig.Hostport
 	}
+	if host == "" {
+		return nil
+	}
 
 	return &url.URL{
 		Scheme: "https",
@@ -194,7 +539,7 @@ func (f *Factory) AlertmanagerService() (*v1.Service, error) {
 
 	s.Namespace = f.namespace
 
-	return s, nil
+	return s, f.exposeService(s, ComponentAlertmanager)
 }
 
 func (f *Factory) AlertmanagerServiceAccount() (*v1.ServiceAccount, error) {
@@ -246,11 +591,17 @@ func (f *Factory) AlertmanagerMain(host string) (*monv1.Alertmanager, error) {
 		a.Spec.Tag = f.config.AlertmanagerMainConfig.Tag
 	}
 
-	a.Spec.ExternalURL = f.AlertmanagerExternalURL(host).String()
+	if u := f.AlertmanagerExternalURL(host); u != nil {
+		a.Spec.ExternalURL = u.String()
+	}
 
 	if f.config.AlertmanagerMainConfig.Resources != nil {
 		a.Spec.Resources = *f.config.AlertmanagerMainConfig.Resources
 	}
+	if r, ok := f.config.AlertmanagerMainConfig.ContainerResources["alertmanager"]; ok {
+		a.Spec.Resources = *r.DeepCopy()
+	}
+	setContainerResources(a.Spec.Containers, f.config.AlertmanagerMainConfig.ContainerResources)
 
 	if f.config.AlertmanagerMainConfig.VolumeClaimTemplate != nil {
 		a.Spec.Storage = &monv1.StorageSpec{
@@ -262,6 +613,16 @@ func (f *Factory) AlertmanagerMain(host string) (*monv1.Alertmanager, error) {
 		a.Spec.NodeSelector = f.config.AlertmanagerMainConfig.NodeSelector
 	}
 
+	// The pods of an Alertmanager are labeled with its name by the
+	// Prometheus Operator.
+	a.Spec.Tolerations = append(a.Spec.Tolerations, f.config.AlertmanagerMainConfig.Tolerations...)
+	a.Spec.Affinity = podAffinity(
+		f.config.AlertmanagerMainConfig.Affinity,
+		f.config.AlertmanagerMainConfig.TopologySpreadConstraints,
+		map[string]string{"app": "alertmanager", "alertmanager": a.Name},
+		true,
+	)
+
 	if f.config.AuthConfig.BaseImage != "" {
 		image, err := imageFromString(a.Spec.Containers[0].Image)
 		if err != nil {
@@ -277,6 +638,15 @@ func (f *Factory) AlertmanagerMain(host string) (*monv1.Alertmanager, error) {
 	return a, nil
 }
 
+func (f *Factory) AlertmanagerIngress() (*v1beta1.Ingress, error) {
+	i, err := f.NewIngress(MustAssetReader(AlertmanagerIngress))
+	if err != nil {
+		return nil, err
+	}
+
+	return i, f.exposeIngress(i, ComponentAlertmanager)
+}
+
 func (f *Factory) AlertmanagerRoute() (*routev1.Route, error) {
 	r, err := f.NewRoute(MustAssetReader(AlertmanagerRoute))
 	if err != nil {
@@ -359,6 +729,9 @@ func (f *Factory) KubeStateMetricsDeployment() (*appsv1.Deployment, error) {
 		d.Spec.Template.Spec.NodeSelector = f.config.KubeStateMetricsConfig.NodeSelector
 	}
 
+	setContainerResources(d.Spec.Template.Spec.Containers, f.config.KubeStateMetricsConfig.ContainerResources)
+	setPodScheduling(&d.Spec.Template, f.config.KubeStateMetricsConfig.Tolerations, f.config.KubeStateMetricsConfig.Affinity, f.config.KubeStateMetricsConfig.TopologySpreadConstraints, false)
+
 	d.Namespace = f.namespace
 
 	return d, nil
@@ -423,6 +796,10 @@ func (f *Factory) NodeExporterDaemonSet() (*appsv1.DaemonSet, error) {
 		image.SetTagIfNotEmpty(f.config.KubeRbacProxyConfig.Tag)
 		ds.Spec.Template.Spec.Containers[1].Image = image.String()
 	}
+
+	setContainerResources(ds.Spec.Template.Spec.Containers, f.config.NodeExporterConfig.ContainerResources)
+	setPodScheduling(&ds.Spec.Template, f.config.NodeExporterConfig.Tolerations, f.config.NodeExporterConfig.Affinity, f.config.NodeExporterConfig.TopologySpreadConstraints, false)
+
 	ds.Namespace = f.namespace
 
 	return ds, nil
@@ -583,9 +960,49 @@ func (f *Factory) PrometheusK8sRules() (*monv1.PrometheusRule, error) {
 		r.Spec.Groups = groups
 	}
 
+	if rc := f.config.PrometheusK8sConfig.Rules; rc != nil {
+		r.Spec.Groups = customizeAlerts(r.Spec.Groups, rc)
+		for _, g := range rc.Groups {
+			g = *g.DeepCopy()
+			g.Name = UserRuleGroupPrefix + g.Name
+			r.Spec.Groups = append(r.Spec.Groups, g)
+		}
+	}
+
 	return r, nil
 }
 
+// customizeAlerts removes the disabled alerts from the groups and applies
+// the alert overrides to them. Groups left without rules are removed.
+func customizeAlerts(groups []monv1.RuleGroup, rc *RulesConfig) []monv1.RuleGroup {
+	if len(rc.DisabledAlerts) == 0 && len(rc.AlertOverrides) == 0 {
+		return groups
+	}
+
+	res := []monv1.RuleGroup{}
+	for _, g := range groups {
+		rules := []monv1.Rule{}
+		for _, r := range g.Rules {
+			if r.Alert != "" && containsString(rc.DisabledAlerts, r.Alert) {
+				continue
+			}
+			// Overrides select the alerts by their default labels.
+			res := r
+			for i := range rc.AlertOverrides {
+				if rc.AlertOverrides[i].matches(r) {
+					res = rc.AlertOverrides[i].apply(res)
+				}
+			}
+			rules = append(rules, res)
+		}
+		if len(rules) > 0 {
+			g.Rules = rules
+			res = append(res, g)
+		}
+	}
+	return res
+}
+
 func (f *Factory) PrometheusK8sServiceAccount() (*v1.ServiceAccount, error) {
 	s, err := f.NewServiceAccount(MustAssetReader(PrometheusK8sServiceAccount))
 	if err != nil {
@@ -613,15 +1030,29 @@ func (f *Factory) PrometheusK8sProxySecret() (*v1.Secret, error) {
 	return s, nil
 }
 
-func (f *Factory) PrometheusK8sHtpasswdSecret(password string) (*v1.Secret, error) {
+// HtpasswdUser is a user the proxy of Prometheus authenticates with basic
+// authentication.
+type HtpasswdUser struct {
+	Name     string
+	Password string
+}
+
+// PrometheusK8sHtpasswdSecret returns the htpasswd file of the proxy of
+// Prometheus, accepting the given users. Accepting more than one user lets
+// Grafana switch to a rotated password without losing access.
+func (f *Factory) PrometheusK8sHtpasswdSecret(users ...HtpasswdUser) (*v1.Secret, error) {
 	s, err := f.NewSecret(MustAssetReader(PrometheusK8sHtpasswd))
 	if err != nil {
 		return nil, err
 	}
 
-	h := sha1.New()
-	h.Write([]byte(password))
-	s.Data["auth"] = []byte("internal:{SHA}" + base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	lines := []string{}
+	for _, u := range users {
+		h := sha1.New()
+		h.Write([]byte(u.Password))
+		lines = append(lines, u.Name+":{SHA}"+base64.StdEncoding.EncodeToString(h.Sum(nil)))
+	}
+	s.Data["auth"] = []byte(strings.Join(lines, "\n"))
 	s.Namespace = f.namespace
 
 	return s, nil
@@ -682,6 +1113,15 @@ func (f *Factory) PrometheusK8sEtcdServiceMonitor() (*monv1.ServiceMonitor, erro
 	return s, nil
 }
 
+func (f *Factory) PrometheusK8sIngress() (*v1beta1.Ingress, error) {
+	i, err := f.NewIngress(MustAssetReader(PrometheusK8sIngress))
+	if err != nil {
+		return nil, err
+	}
+
+	return i, f.exposeIngress(i, ComponentPrometheusK8s)
+}
+
 func (f *Factory) PrometheusK8sRoute() (*routev1.Route, error) {
 	r, err := f.NewRoute(MustAssetReader(PrometheusK8sRoute))
 	if err != nil {
@@ -711,16 +1151,32 @@ func (f *Factory) PrometheusK8s(host string) (*monv1.Prometheus, error) {
 		p.Spec.Tag = f.config.PrometheusK8sConfig.Tag
 	}
 
-	p.Spec.ExternalURL = f.PrometheusExternalURL(host).String()
+	if u := f.PrometheusExternalURL(host); u != nil {
+		p.Spec.ExternalURL = u.String()
+	}
 
 	if f.config.PrometheusK8sConfig.Resources != nil {
 		p.Spec.Resources = *f.config.PrometheusK8sConfig.Resources
 	}
+	if r, ok := f.config.PrometheusK8sConfig.ContainerResources["prometheus"]; ok {
+		p.Spec.Resources = *r.DeepCopy()
+	}
+	setContainerResources(p.Spec.Containers, f.config.PrometheusK8sConfig.ContainerResources)
 
 	if f.config.PrometheusK8sConfig.NodeSelector != nil {
 		p.Spec.NodeSelector = f.config.PrometheusK8sConfig.NodeSelector
 	}
 
+	// The pods of a Prometheus are labeled with its name by the Prometheus
+	// Operator.
+	p.Spec.Tolerations = append(p.Spec.Tolerations, f.config.PrometheusK8sConfig.Tolerations...)
+	p.Spec.Affinity = podAffinity(
+		f.config.PrometheusK8sConfig.Affinity,
+		f.config.PrometheusK8sConfig.TopologySpreadConstraints,
+		map[string]string{"app": "prometheus", "prometheus": p.Name},
+		true,
+	)
+
 	if f.config.PrometheusK8sConfig.ExternalLabels != nil {
 		p.Spec.ExternalLabels = f.config.PrometheusK8sConfig.ExternalLabels
 	}
@@ -742,6 +1198,17 @@ func (f *Factory) PrometheusK8s(host string) (*monv1.Prometheus, error) {
 		p.Spec.Secrets = secrets
 	}
 
+	for _, rw := range f.config.PrometheusK8sConfig.RemoteWrite {
+		p.Spec.RemoteWrite = append(p.Spec.RemoteWrite, remoteWriteSpec(rw))
+		// Basic auth credentials are read by the Prometheus Operator
+		// itself, the other Secrets have to be mounted.
+		for _, sel := range rw.mountedSecrets() {
+			if !containsString(p.Spec.Secrets, sel.Name) {
+				p.Spec.Secrets = append(p.Spec.Secrets, sel.Name)
+			}
+		}
+	}
+
 	if f.config.AuthConfig.BaseImage != "" {
 		image, err := imageFromString(p.Spec.Containers[0].Image)
 		if err != nil {
@@ -759,6 +1226,82 @@ func (f *Factory) PrometheusK8s(host string) (*monv1.Prometheus, error) {
 	return p, nil
 }
 
+// SetRemoteWriteSecretsHash sets RemoteWriteSecretsHashAnnotation on the pods
+// of the Prometheus object p to the hash of the data of the Secrets
+// referenced by its remote write endpoints, if there are any. Prometheus
+// only reads the mounted credentials when it starts, and the Prometheus
+// Operator only reads the basic auth credentials when the Prometheus object
+// changes.
+func SetRemoteWriteSecretsHash(p *monv1.Prometheus, secrets []*v1.Secret) {
+	if len(secrets) == 0 {
+		return
+	}
+
+	h := sha256.New()
+	for _, s := range secrets {
+		keys := make([]string, 0, len(s.Data))
+		for k := range s.Data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		fmt.Fprintf(h, "%s\n", s.GetName())
+		for _, k := range keys {
+			fmt.Fprintf(h, "%s=%x\n", k, s.Data[k])
+		}
+	}
+
+	if p.Spec.PodMetadata == nil {
+		p.Spec.PodMetadata = &metav1.ObjectMeta{}
+	}
+	if p.Spec.PodMetadata.Annotations == nil {
+		p.Spec.PodMetadata.Annotations = map[string]string{}
+	}
+	p.Spec.PodMetadata.Annotations[RemoteWriteSecretsHashAnnotation] = hex.EncodeToString(h.Sum(nil))
+}
+
+// remoteWriteSpec maps a configured remote write endpoint onto the one of
+// the Prometheus resource, referencing the mounted Secrets by path.
+func remoteWriteSpec(rw RemoteWriteSpec) monv1.RemoteWriteSpec {
+	res := monv1.RemoteWriteSpec{
+		URL:           rw.URL,
+		RemoteTimeout: rw.RemoteTimeout,
+		ProxyURL:      rw.ProxyURL,
+	}
+	for _, rc := range rw.WriteRelabelConfigs {
+		res.WriteRelabelConfigs = append(res.WriteRelabelConfigs, *rc.DeepCopy())
+	}
+	if rw.QueueConfig != nil {
+		res.QueueConfig = rw.QueueConfig.DeepCopy()
+	}
+	if rw.BasicAuth != nil {
+		res.BasicAuth = rw.BasicAuth.DeepCopy()
+	}
+	if rw.BearerToken != nil {
+		res.BearerTokenFile = prometheusSecretPath(rw.BearerToken)
+	}
+	if rw.TLSConfig != nil {
+		res.TLSConfig = &monv1.TLSConfig{
+			CAFile:             prometheusSecretPath(rw.TLSConfig.CA),
+			CertFile:           prometheusSecretPath(rw.TLSConfig.Cert),
+			KeyFile:            prometheusSecretPath(rw.TLSConfig.Key),
+			ServerName:         rw.TLSConfig.ServerName,
+			InsecureSkipVerify: rw.TLSConfig.InsecureSkipVerify,
+		}
+	}
+	return res
+}
+
+// prometheusSecretPath returns the path of the key of a Secret listed in
+// the secrets of a Prometheus resource, which the Prometheus Operator mounts
+// in the Prometheus container, or an empty path if sel is nil.
+func prometheusSecretPath(sel *v1.SecretKeySelector) string {
+	if sel == nil {
+		return ""
+	}
+	return path.Join(prometheusSecretsDir, sel.Name, sel.Key)
+}
+
 func (f *Factory) PrometheusK8sKubeletServiceMonitor() (*monv1.ServiceMonitor, error) {
 	s, err := f.NewServiceMonitor(MustAssetReader(PrometheusK8sKubeletServiceMonitor))
 	if err != nil {
@@ -884,6 +1427,10 @@ func (f *Factory) PrometheusOperatorDeployment() (*appsv1.Deployment, error) {
 		}
 	}
 	d.Spec.Template.Spec.Containers[0].Args = args
+
+	setContainerResources(d.Spec.Template.Spec.Containers, f.config.PrometheusOperatorConfig.ContainerResources)
+	setPodScheduling(&d.Spec.Template, f.config.PrometheusOperatorConfig.Tolerations, f.config.PrometheusOperatorConfig.Affinity, f.config.PrometheusOperatorConfig.TopologySpreadConstraints, false)
+
 	d.Namespace = f.namespace
 
 	return d, nil
@@ -900,6 +1447,28 @@ func (f *Factory) PrometheusOperatorService() (*v1.Service, error) {
 	return s, nil
 }
 
+func (f *Factory) ClusterMonitoringOperatorService() (*v1.Service, error) {
+	s, err := f.NewService(MustAssetReader(ClusterMonitoringOperatorService))
+	if err != nil {
+		return nil, err
+	}
+
+	s.Namespace = f.namespace
+
+	return s, nil
+}
+
+func (f *Factory) ClusterMonitoringOperatorServiceMonitor() (*monv1.ServiceMonitor, error) {
+	sm, err := f.NewServiceMonitor(MustAssetReader(ClusterMonitoringOperatorServiceMonitor))
+	if err != nil {
+		return nil, err
+	}
+
+	sm.Namespace = f.namespace
+
+	return sm, nil
+}
+
 func (f *Factory) PrometheusK8sService() (*v1.Service, error) {
 	s, err := f.NewService(MustAssetReader(PrometheusK8sService))
 	if err != nil {
@@ -908,7 +1477,7 @@ func (f *Factory) PrometheusK8sService() (*v1.Service, error) {
 
 	s.Namespace = f.namespace
 
-	return s, nil
+	return s, f.exposeService(s, ComponentPrometheusK8s)
 }
 
 func (f *Factory) KubeControllersService() (*v1.Service, error) {
@@ -964,18 +1533,60 @@ type GrafanaJsonData struct {
 	TlsSkipVerify bool `json:"tlsSkipVerify"`
 }
 
+// GrafanaDatasourceUsers are the users Grafana authenticates to Prometheus
+// as. Rotating the password of the datasource alternates between them, as
+// Prometheus accepts both the old and the new user while Grafana is
+// restarted.
+var GrafanaDatasourceUsers = [2]string{"internal", "internal-rotated"}
+
+// ParseGrafanaDatasources returns the datasources of the datasources Secret
+// of Grafana, the first one being Prometheus.
+func ParseGrafanaDatasources(s *v1.Secret) (*GrafanaDatasources, error) {
+	d := &GrafanaDatasources{}
+	if err := json.Unmarshal(s.Data["prometheus.yaml"], d); err != nil {
+		return nil, errors.Wrap(err, "decoding Grafana datasources failed")
+	}
+	if len(d.Datasources) == 0 {
+		return nil, errors.New("the Grafana datasources hold no Prometheus datasource")
+	}
+	return d, nil
+}
+
+// GrafanaDatasources returns the datasources Secret of Grafana with a newly
+// generated password.
 func (f *Factory) GrafanaDatasources() (*v1.Secret, error) {
+	return f.grafanaDatasources(nil)
+}
+
+// RotatedGrafanaDatasources returns the datasources Secret of Grafana with a
+// newly generated password for the other user of GrafanaDatasourceUsers
+// than the one of previous. The version of the datasource is increased, so
+// that Grafana updates it.
+func (f *Factory) RotatedGrafanaDatasources(previous *GrafanaDatasources) (*v1.Secret, error) {
+	return f.grafanaDatasources(previous.Datasources[0])
+}
+
+func (f *Factory) grafanaDatasources(previous *GrafanaDatasource) (*v1.Secret, error) {
 	s, err := f.NewSecret(MustAssetReader(GrafanaDatasourcesSecret))
 	if err != nil {
 		return nil, err
 	}
 
-	d := &GrafanaDatasources{}
-	err = json.Unmarshal(s.Data["prometheus.yaml"], d)
+	d, err := ParseGrafanaDatasources(s)
 	if err != nil {
 		return nil, err
 	}
-	d.Datasources[0].BasicAuthPassword, err = GeneratePassword(255)
+	ds := d.Datasources[0]
+	if previous != nil {
+		ds.BasicAuthUser = GrafanaDatasourceUsers[0]
+		if previous.BasicAuthUser == GrafanaDatasourceUsers[0] {
+			ds.BasicAuthUser = GrafanaDatasourceUsers[1]
+		}
+		if previous.Version >= ds.Version {
+			ds.Version = previous.Version + 1
+		}
+	}
+	ds.BasicAuthPassword, err = GeneratePassword(255)
 	if err != nil {
 		return nil, err
 	}
@@ -1072,6 +1683,9 @@ func (f *Factory) GrafanaDeployment() (*appsv1.Deployment, error) {
 		d.Spec.Template.Spec.NodeSelector = f.config.GrafanaConfig.NodeSelector
 	}
 
+	setContainerResources(d.Spec.Template.Spec.Containers, f.config.GrafanaConfig.ContainerResources)
+	setPodScheduling(&d.Spec.Template, f.config.GrafanaConfig.Tolerations, f.config.GrafanaConfig.Affinity, f.config.GrafanaConfig.TopologySpreadConstraints, false)
+
 	d.Namespace = f.namespace
 
 	return d, nil
@@ -1093,6 +1707,15 @@ func (f *Factory) GrafanaProxySecret() (*v1.Secret, error) {
 	return s, nil
 }
 
+func (f *Factory) GrafanaIngress() (*v1beta1.Ingress, error) {
+	i, err := f.NewIngress(MustAssetReader(GrafanaIngress))
+	if err != nil {
+		return nil, err
+	}
+
+	return i, f.exposeIngress(i, ComponentGrafana)
+}
+
 func (f *Factory) GrafanaRoute() (*routev1.Route, error) {
 	r, err := f.NewRoute(MustAssetReader(GrafanaRoute))
 	if err != nil {
@@ -1126,7 +1749,7 @@ func (f *Factory) GrafanaService() (*v1.Service, error) {
 
 	s.Namespace = f.namespace
 
-	return s, nil
+	return s, f.exposeService(s, ComponentGrafana)
 }
 
 func hostFromBaseAddress(baseAddress string) (string, error) {
@@ -1162,6 +1785,8 @@ func (f *Factory) NewDaemonSet(manifest io.Reader) (*appsv1.DaemonSet, error) {
 		ds.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(ds, manifest)
+
 	return ds, nil
 }
 
@@ -1175,6 +1800,8 @@ func (f *Factory) NewService(manifest io.Reader) (*v1.Service, error) {
 		s.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(s, manifest)
+
 	return s, nil
 }
 
@@ -1188,6 +1815,8 @@ func (f *Factory) NewEndpoints(manifest io.Reader) (*v1.Endpoints, error) {
 		e.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(e, manifest)
+
 	return e, nil
 }
 
@@ -1201,6 +1830,8 @@ func (f *Factory) NewRoute(manifest io.Reader) (*routev1.Route, error) {
 		r.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(r, manifest)
+
 	return r, nil
 }
 
@@ -1214,6 +1845,8 @@ func (f *Factory) NewSecret(manifest io.Reader) (*v1.Secret, error) {
 		s.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(s, manifest)
+
 	return s, nil
 }
 
@@ -1227,6 +1860,8 @@ func (f *Factory) NewRoleBinding(manifest io.Reader) (*rbacv1beta1.RoleBinding,
 		rb.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(rb, manifest)
+
 	return rb, nil
 }
 
@@ -1240,6 +1875,8 @@ func (f *Factory) NewRole(manifest io.Reader) (*rbacv1beta1.Role, error) {
 		r.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(r, manifest)
+
 	return r, nil
 }
 
@@ -1253,6 +1890,8 @@ func (f *Factory) NewConfigMap(manifest io.Reader) (*v1.ConfigMap, error) {
 		cm.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(cm, manifest)
+
 	return cm, nil
 }
 
@@ -1262,10 +1901,12 @@ func (f *Factory) NewConfigMapList(manifest io.Reader) (*v1.ConfigMapList, error
 		return nil, err
 	}
 
-	for _, cm := range cml.Items {
+	for i := range cml.Items {
+		cm := &cml.Items[i]
 		if cm.GetNamespace() == "" {
 			cm.SetNamespace(f.namespace)
 		}
+		setManagedLabels(cm, manifest)
 	}
 
 	return cml, nil
@@ -1281,6 +1922,8 @@ func (f *Factory) NewServiceAccount(manifest io.Reader) (*v1.ServiceAccount, err
 		sa.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(sa, manifest)
+
 	return sa, nil
 }
 
@@ -1294,6 +1937,8 @@ func (f *Factory) NewPrometheus(manifest io.Reader) (*monv1.Prometheus, error) {
 		p.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(p, manifest)
+
 	return p, nil
 }
 
@@ -1307,6 +1952,8 @@ func (f *Factory) NewPrometheusRule(manifest io.Reader) (*monv1.PrometheusRule,
 		p.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(p, manifest)
+
 	return p, nil
 }
 
@@ -1320,6 +1967,8 @@ func (f *Factory) NewAlertmanager(manifest io.Reader) (*monv1.Alertmanager, erro
 		a.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(a, manifest)
+
 	return a, nil
 }
 
@@ -1333,6 +1982,8 @@ func (f *Factory) NewServiceMonitor(manifest io.Reader) (*monv1.ServiceMonitor,
 		sm.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(sm, manifest)
+
 	return sm, nil
 }
 
@@ -1346,6 +1997,8 @@ func (f *Factory) NewDeployment(manifest io.Reader) (*appsv1.Deployment, error)
 		d.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(d, manifest)
+
 	return d, nil
 }
 
@@ -1359,19 +2012,42 @@ func (f *Factory) NewIngress(manifest io.Reader) (*v1beta1.Ingress, error) {
 		i.SetNamespace(f.namespace)
 	}
 
+	setManagedLabels(i, manifest)
+
 	return i, nil
 }
 
 func (f *Factory) NewSecurityContextConstraints(manifest io.Reader) (*securityv1.SecurityContextConstraints, error) {
-	return NewSecurityContextConstraints(manifest)
+	scc, err := NewSecurityContextConstraints(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	setManagedLabels(scc, manifest)
+
+	return scc, nil
 }
 
 func (f *Factory) NewClusterRoleBinding(manifest io.Reader) (*rbacv1beta1.ClusterRoleBinding, error) {
-	return NewClusterRoleBinding(manifest)
+	crb, err := NewClusterRoleBinding(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	setManagedLabels(crb, manifest)
+
+	return crb, nil
 }
 
 func (f *Factory) NewClusterRole(manifest io.Reader) (*rbacv1beta1.ClusterRole, error) {
-	return NewClusterRole(manifest)
+	cr, err := NewClusterRole(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	setManagedLabels(cr, manifest)
+
+	return cr, nil
 }
 
 func NewDaemonSet(manifest io.Reader) (*appsv1.DaemonSet, error) {
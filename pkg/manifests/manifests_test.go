@@ -15,12 +15,20 @@
 package manifests
 
 import (
+	"fmt"
+	"io/ioutil"
 	"reflect"
 	"sort"
 	"strings"
 	"testing"
 
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/ghodss/yaml"
+	routev1 "github.com/openshift/api/route/v1"
+	appsv1 "k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	rbacv1beta1 "k8s.io/api/rbac/v1beta1"
 )
 
 func TestUnconfiguredManifests(t *testing.T) {
@@ -274,6 +282,16 @@ func TestUnconfiguredManifests(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	_, err = f.ClusterMonitoringOperatorService()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = f.ClusterMonitoringOperatorServiceMonitor()
+	if err != nil {
+		t.Fatal(err)
+	}
 }
 
 func TestPrometheusOperatorConfiguration(t *testing.T) {
@@ -566,6 +584,113 @@ func TestPrometheusEtcdRules(t *testing.T) {
 	}
 }
 
+func TestPrometheusUserRules(t *testing.T) {
+	c, err := NewConfigFromString(`prometheusK8s:
+  rules:
+    groups:
+    - name: k8s.rules
+      rules:
+      - alert: PlatformDown
+        expr: up{job="platform"} == 0
+        for: 10m
+        labels:
+          severity: critical
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defaults, err := NewFactory("openshift-monitoring", NewDefaultConfig()).PrometheusK8sRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewFactory("openshift-monitoring", c).PrometheusK8sRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The user group is added to the defaults, and its name does not
+	// collide with the default group of the same name.
+	if len(r.Spec.Groups) != len(defaults.Spec.Groups)+1 {
+		t.Fatalf("expected %d groups, got %d", len(defaults.Spec.Groups)+1, len(r.Spec.Groups))
+	}
+	if !reflect.DeepEqual(r.Spec.Groups[:len(defaults.Spec.Groups)], defaults.Spec.Groups) {
+		t.Error("expected the default groups to be kept")
+	}
+	g := r.Spec.Groups[len(r.Spec.Groups)-1]
+	if g.Name != "user/k8s.rules" {
+		t.Errorf("expected the group name to be prefixed, got %q", g.Name)
+	}
+	if len(g.Rules) != 1 || g.Rules[0].Alert != "PlatformDown" || g.Rules[0].For != "10m" || g.Rules[0].Labels["severity"] != "critical" {
+		t.Errorf("unexpected rules %v", g.Rules)
+	}
+	if c.PrometheusK8sConfig.Rules.Groups[0].Name != "k8s.rules" {
+		t.Error("expected the configuration to be left untouched")
+	}
+}
+
+func TestPrometheusAlertOverrides(t *testing.T) {
+	c, err := NewConfigFromString(`prometheusK8s:
+  rules:
+    disabledAlerts:
+    - KubePodCrashLooping
+    alertOverrides:
+    - alert: NodeDiskRunningFull
+      matchLabels:
+        severity: warning
+      for: 1h
+      labels:
+        severity: info
+      annotations:
+        summary: ""
+    - alert: NodeDiskRunningFull
+      matchLabels:
+        severity: critical
+      labels:
+        severity: warning
+        team: storage
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewFactory("openshift-monitoring", c).PrometheusK8sRules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diskAlerts := []monv1.Rule{}
+	for _, g := range r.Spec.Groups {
+		for _, rule := range g.Rules {
+			switch rule.Alert {
+			case "KubePodCrashLooping":
+				t.Error("expected the disabled alert to be removed")
+			case "NodeDiskRunningFull":
+				diskAlerts = append(diskAlerts, rule)
+			}
+		}
+	}
+	if len(diskAlerts) != 2 {
+		t.Fatalf("expected 2 NodeDiskRunningFull alerts, got %d", len(diskAlerts))
+	}
+
+	// Overrides select alerts by their default labels, so the severities
+	// are not changed twice.
+	warning, critical := diskAlerts[0], diskAlerts[1]
+	if warning.For != "1h" || warning.Labels["severity"] != "info" || warning.Labels["team"] != "" {
+		t.Errorf("unexpected overridden warning alert: for %q, labels %v", warning.For, warning.Labels)
+	}
+	if _, ok := warning.Annotations["summary"]; ok || warning.Annotations["description"] == "" {
+		t.Errorf("expected only the summary annotation to be removed, got %v", warning.Annotations)
+	}
+	if critical.For != "10m" || critical.Labels["severity"] != "warning" || critical.Labels["team"] != "storage" {
+		t.Errorf("unexpected overridden critical alert: for %q, labels %v", critical.For, critical.Labels)
+	}
+	if critical.Annotations["summary"] == "" {
+		t.Error("expected the annotations of the critical alert to be kept")
+	}
+}
+
 func TestEtcdGrafanaDashboardFiltered(t *testing.T) {
 	f := NewFactory("openshift-monitoring", NewDefaultConfig())
 
@@ -604,3 +729,581 @@ func TestEtcdGrafanaDashboard(t *testing.T) {
 		t.Fatal("etcd dashboard not found, even if etcd is enabled")
 	}
 }
+
+func TestManagedLabels(t *testing.T) {
+	f := NewFactory("openshift-monitoring", NewDefaultConfig())
+
+	d, err := f.GrafanaDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Labels[ManagedByLabel] != ManagedByValue {
+		t.Fatalf("expected %s label to be %q, got %q", ManagedByLabel, ManagedByValue, d.Labels[ManagedByLabel])
+	}
+	if d.Labels[ComponentLabel] != ComponentGrafana {
+		t.Fatalf("expected %s label to be %q, got %q", ComponentLabel, ComponentGrafana, d.Labels[ComponentLabel])
+	}
+
+	cml, err := f.GrafanaDashboardDefinitions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cm := range cml.Items {
+		if cm.Namespace != "openshift-monitoring" {
+			t.Fatalf("expected ConfigMap %s to be in namespace openshift-monitoring, got %q", cm.Name, cm.Namespace)
+		}
+		if cm.Labels[ComponentLabel] != ComponentGrafana {
+			t.Fatalf("expected %s label of ConfigMap %s to be %q, got %q", ComponentLabel, cm.Name, ComponentGrafana, cm.Labels[ComponentLabel])
+		}
+	}
+}
+
+func TestExposure(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		platform Platform
+		config   string
+		kind     string
+		err      bool
+	}{
+		{
+			name:     "Route by default on OpenShift",
+			platform: OpenShiftPlatform,
+			kind:     "Route",
+		}, {
+			name:     "not exposed by default on Kubernetes",
+			platform: KubernetesPlatform,
+		}, {
+			name:     "Route not served",
+			platform: KubernetesPlatform,
+			config: `prometheusK8s:
+  expose:
+    type: Route`,
+			err: true,
+		}, {
+			name:     "Ingress",
+			platform: KubernetesPlatform,
+			config: `prometheusK8s:
+  hostport: prometheus.example.com
+  expose:
+    type: Ingress
+    tlsSecretName: prometheus-tls
+    annotations:
+      kubernetes.io/ingress.class: nginx`,
+			kind: "Ingress",
+		}, {
+			name:     "LoadBalancer",
+			platform: KubernetesPlatform,
+			config: `prometheusK8s:
+  expose:
+    type: LoadBalancer`,
+			kind: "Service",
+		},
+	} {
+		c, err := NewConfigFromString(tc.config)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		c.SetPlatform(tc.platform)
+		f := NewFactory("openshift-monitoring", c)
+
+		obj, err := f.Exposure(ComponentPrometheusK8s)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		switch o := obj.(type) {
+		case nil:
+			if tc.kind != "" {
+				t.Errorf("%s: expected a %s, got none", tc.name, tc.kind)
+			}
+		case *routev1.Route:
+			if tc.kind != "Route" {
+				t.Errorf("%s: expected a %s, got a Route", tc.name, tc.kind)
+			}
+		case *v1beta1.Ingress:
+			if tc.kind != "Ingress" {
+				t.Fatalf("%s: expected a %s, got an Ingress", tc.name, tc.kind)
+			}
+			if o.Namespace != "openshift-monitoring" {
+				t.Errorf("%s: expected namespace openshift-monitoring, got %q", tc.name, o.Namespace)
+			}
+			if o.Spec.Rules[0].Host != "prometheus.example.com" {
+				t.Errorf("%s: expected host prometheus.example.com, got %q", tc.name, o.Spec.Rules[0].Host)
+			}
+			if len(o.Spec.TLS) != 1 || o.Spec.TLS[0].SecretName != "prometheus-tls" {
+				t.Errorf("%s: expected TLS with secret prometheus-tls, got %v", tc.name, o.Spec.TLS)
+			}
+			if o.Annotations["kubernetes.io/ingress.class"] != "nginx" {
+				t.Errorf("%s: expected the annotations to be set, got %v", tc.name, o.Annotations)
+			}
+		case *v1.Service:
+			if tc.kind != "Service" {
+				t.Fatalf("%s: expected a %s, got a Service", tc.name, tc.kind)
+			}
+			if o.Spec.Type != v1.ServiceTypeLoadBalancer {
+				t.Errorf("%s: expected type LoadBalancer, got %q", tc.name, o.Spec.Type)
+			}
+		default:
+			t.Errorf("%s: unexpected object %T", tc.name, obj)
+		}
+	}
+}
+
+func TestRotatedGrafanaDatasources(t *testing.T) {
+	f := NewFactory("openshift-monitoring", NewDefaultConfig())
+
+	s, err := f.GrafanaDatasources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous, err := ParseGrafanaDatasources(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if previous.Datasources[0].BasicAuthUser != GrafanaDatasourceUsers[0] {
+		t.Fatalf("expected the user %q, got %q", GrafanaDatasourceUsers[0], previous.Datasources[0].BasicAuthUser)
+	}
+
+	// The user alternates, so that Prometheus can accept the previous and
+	// the rotated credentials at the same time.
+	for i := 1; i <= 3; i++ {
+		s, err := f.RotatedGrafanaDatasources(previous)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d, err := ParseGrafanaDatasources(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ds, prev := d.Datasources[0], previous.Datasources[0]
+		if expected := GrafanaDatasourceUsers[i%2]; ds.BasicAuthUser != expected {
+			t.Errorf("rotation %d: expected the user %q, got %q", i, expected, ds.BasicAuthUser)
+		}
+		if ds.BasicAuthPassword == prev.BasicAuthPassword {
+			t.Errorf("rotation %d: expected a new password", i)
+		}
+		if ds.Version <= prev.Version {
+			t.Errorf("rotation %d: expected a version greater than %d, got %d", i, prev.Version, ds.Version)
+		}
+		previous = d
+	}
+}
+
+func TestPrometheusK8sHtpasswdSecret(t *testing.T) {
+	f := NewFactory("openshift-monitoring", NewDefaultConfig())
+
+	s, err := f.PrometheusK8sHtpasswdSecret(HtpasswdUser{"internal", "a"}, HtpasswdUser{"internal-rotated", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The passwords are the base64 encoded SHA1 sums of "a" and "b".
+	expected := "internal:{SHA}hvfkN/qlp/zhXR3cuerq6jd2Z7g=\ninternal-rotated:{SHA}6dcfXufJLW3J6S/9rRe4vUlBj5g="
+	if got := string(s.Data["auth"]); got != expected {
+		t.Errorf("expected htpasswd %q, got %q", expected, got)
+	}
+}
+
+func TestScheduling(t *testing.T) {
+	zone := "failure-domain.beta.kubernetes.io/zone"
+	infra := v1.Toleration{Key: "node-role.kubernetes.io/infra", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule}
+
+	// The replicated StatefulSets are spread across nodes by default.
+	f := NewFactory("openshift-monitoring", NewDefaultConfig())
+	p, err := f.PrometheusK8s("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	preferred := p.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(preferred) != 1 || preferred[0].PodAffinityTerm.TopologyKey != hostnameTopologyKey {
+		t.Fatalf("expected soft pod anti-affinity across nodes, got %+v", p.Spec.Affinity)
+	}
+	if expected := map[string]string{"app": "prometheus", "prometheus": "k8s"}; !reflect.DeepEqual(preferred[0].PodAffinityTerm.LabelSelector.MatchLabels, expected) {
+		t.Errorf("expected the pods of Prometheus to be selected by %v, got %v", expected, preferred[0].PodAffinityTerm.LabelSelector.MatchLabels)
+	}
+	a, err := f.AlertmanagerMain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Spec.Affinity == nil || len(a.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution) != 1 {
+		t.Errorf("expected soft pod anti-affinity for Alertmanager, got %+v", a.Spec.Affinity)
+	}
+	g, err := f.GrafanaDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Spec.Template.Spec.Affinity != nil {
+		t.Errorf("expected no affinity for Grafana, got %+v", g.Spec.Template.Spec.Affinity)
+	}
+
+	c, err := NewConfigFromString(`prometheusK8s:
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+    effect: NoSchedule
+  topologySpreadConstraints:
+  - maxSkew: 1
+    topologyKey: failure-domain.beta.kubernetes.io/zone
+    whenUnsatisfiable: ScheduleAnyway
+alertmanagerMain:
+  affinity:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+        - matchExpressions:
+          - key: node-role.kubernetes.io/infra
+            operator: Exists
+prometheusOperator:
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+    effect: NoSchedule
+nodeExporter:
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+    effect: NoSchedule
+kubeStateMetrics:
+  topologySpreadConstraints:
+  - topologyKey: failure-domain.beta.kubernetes.io/zone
+grafana:
+  tolerations:
+  - key: node-role.kubernetes.io/infra
+    operator: Exists
+    effect: NoSchedule
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = NewFactory("openshift-monitoring", c)
+
+	// Topology spread constraints are added to the default pod
+	// anti-affinity.
+	p, err = f.PrometheusK8s("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(p.Spec.Tolerations, []v1.Toleration{infra}) {
+		t.Errorf("expected tolerations %v, got %v", []v1.Toleration{infra}, p.Spec.Tolerations)
+	}
+	if required := p.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution; len(required) != 0 {
+		t.Errorf("expected no required pod anti-affinity, got %+v", required)
+	}
+	preferred = p.Spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+	if len(preferred) != 2 {
+		t.Fatalf("expected the default pod anti-affinity to be kept, got %+v", p.Spec.Affinity.PodAntiAffinity)
+	}
+	if term := preferred[1].PodAffinityTerm; term.TopologyKey != zone || term.LabelSelector.MatchLabels["prometheus"] != "k8s" {
+		t.Errorf("expected preferred pod anti-affinity across zones, got %+v", term)
+	}
+
+	// A configured affinity replaces the default one.
+	a, err = f.AlertmanagerMain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Spec.Affinity.NodeAffinity == nil || a.Spec.Affinity.PodAntiAffinity != nil {
+		t.Errorf("expected the configured affinity only, got %+v", a.Spec.Affinity)
+	}
+
+	// Tolerations are added to the ones of the manifests.
+	ds, err := f.NodeExporterDaemonSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tolerations := ds.Spec.Template.Spec.Tolerations; len(tolerations) != 2 || tolerations[1] != infra {
+		t.Errorf("expected the infra toleration to be added, got %v", tolerations)
+	}
+
+	for _, tc := range []struct {
+		name        string
+		deployment  func() (*appsv1.Deployment, error)
+		tolerations []v1.Toleration
+		spread      bool
+	}{
+		{name: "prometheus-operator", deployment: f.PrometheusOperatorDeployment, tolerations: []v1.Toleration{infra}},
+		{name: "grafana", deployment: f.GrafanaDeployment, tolerations: []v1.Toleration{infra}},
+		{name: "kube-state-metrics", deployment: f.KubeStateMetricsDeployment, spread: true},
+	} {
+		d, err := tc.deployment()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		spec := d.Spec.Template.Spec
+		if !reflect.DeepEqual(spec.Tolerations, tc.tolerations) {
+			t.Errorf("%s: expected tolerations %v, got %v", tc.name, tc.tolerations, spec.Tolerations)
+		}
+		if !tc.spread {
+			if spec.Affinity != nil {
+				t.Errorf("%s: expected no affinity, got %+v", tc.name, spec.Affinity)
+			}
+			continue
+		}
+		preferred := spec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution
+		if len(preferred) != 1 || preferred[0].PodAffinityTerm.TopologyKey != zone || !reflect.DeepEqual(preferred[0].PodAffinityTerm.LabelSelector.MatchLabels, d.Spec.Template.Labels) {
+			t.Errorf("%s: expected soft pod anti-affinity across zones, got %+v", tc.name, spec.Affinity)
+		}
+	}
+}
+
+func TestContainerResources(t *testing.T) {
+	// Every configurable container gets its own memory limit, so that
+	// containers missing from the manifests are told apart.
+	config := ""
+	limits := map[string]string{}
+	for _, c := range []struct {
+		key        string
+		containers []string
+	}{
+		{"prometheusOperator", prometheusOperatorContainers},
+		{"prometheusK8s", prometheusK8sContainers},
+		{"alertmanagerMain", alertmanagerMainContainers},
+		{"grafana", grafanaContainers},
+		{"nodeExporter", nodeExporterContainers},
+		{"kubeStateMetrics", kubeStateMetricsContainers},
+	} {
+		config += c.key + ":\n  containerResources:\n"
+		for _, name := range c.containers {
+			limits[c.key+"/"+name] = fmt.Sprintf("%dMi", len(limits)+1)
+			config += fmt.Sprintf("    %s:\n      limits:\n        memory: %s\n", name, limits[c.key+"/"+name])
+		}
+	}
+
+	c, err := NewConfigFromString(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := NewFactory("openshift-monitoring", c)
+
+	got := map[string]string{}
+	record := func(key, name string, r v1.ResourceRequirements) {
+		if q, ok := r.Limits[v1.ResourceMemory]; ok {
+			got[key+"/"+name] = q.String()
+		}
+	}
+	recordContainers := func(key string, containers []v1.Container) {
+		for _, c := range containers {
+			record(key, c.Name, c.Resources)
+		}
+	}
+
+	po, err := f.PrometheusOperatorDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordContainers("prometheusOperator", po.Spec.Template.Spec.Containers)
+	p, err := f.PrometheusK8s("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record("prometheusK8s", "prometheus", p.Spec.Resources)
+	recordContainers("prometheusK8s", p.Spec.Containers)
+	a, err := f.AlertmanagerMain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record("alertmanagerMain", "alertmanager", a.Spec.Resources)
+	recordContainers("alertmanagerMain", a.Spec.Containers)
+	g, err := f.GrafanaDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordContainers("grafana", g.Spec.Template.Spec.Containers)
+	ne, err := f.NodeExporterDaemonSet()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordContainers("nodeExporter", ne.Spec.Template.Spec.Containers)
+	ksm, err := f.KubeStateMetricsDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordContainers("kubeStateMetrics", ksm.Spec.Template.Spec.Containers)
+
+	if !reflect.DeepEqual(got, limits) {
+		t.Errorf("unexpected memory limits\nexpected: %v\ngot:      %v", limits, got)
+	}
+
+	// Containers without configured resources keep the ones of the
+	// manifests.
+	f = NewFactory("openshift-monitoring", NewDefaultConfig())
+	ksm, err = f.KubeStateMetricsDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q := ksm.Spec.Template.Spec.Containers[0].Resources.Limits[v1.ResourceMemory]; q.IsZero() {
+		t.Errorf("expected the memory limit of the manifest for %s", ksm.Spec.Template.Spec.Containers[0].Name)
+	}
+}
+
+func TestRemoteWrite(t *testing.T) {
+	c, err := NewConfigFromString(`prometheusK8s:
+  remoteWrite:
+  - url: https://remote.example.com/api/v1/write
+    writeRelabelConfigs:
+    - sourceLabels: [__name__]
+      regex: "up"
+      action: keep
+    queueConfig:
+      maxShards: 10
+    basicAuth:
+      username:
+        name: remote-write-auth
+        key: user
+      password:
+        name: remote-write-auth
+        key: password
+    tlsConfig:
+      ca:
+        name: remote-write-tls
+        key: ca.crt
+      serverName: remote.example.com
+  - url: https://other.example.com/write
+    bearerToken:
+      name: remote-write-token
+      key: token
+    tlsConfig:
+      ca:
+        name: remote-write-tls
+        key: ca.crt
+      cert:
+        name: remote-write-tls
+        key: tls.crt
+      key:
+        name: remote-write-tls
+        key: tls.key
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := NewFactory("openshift-monitoring", c).PrometheusK8s("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(p.Spec.RemoteWrite) != 2 {
+		t.Fatalf("expected 2 remote write endpoints, got %d", len(p.Spec.RemoteWrite))
+	}
+	first, second := p.Spec.RemoteWrite[0], p.Spec.RemoteWrite[1]
+	if first.URL != "https://remote.example.com/api/v1/write" || second.URL != "https://other.example.com/write" {
+		t.Errorf("unexpected URLs %q and %q", first.URL, second.URL)
+	}
+	if len(first.WriteRelabelConfigs) != 1 || first.WriteRelabelConfigs[0].Action != "keep" {
+		t.Errorf("unexpected write relabel configs %v", first.WriteRelabelConfigs)
+	}
+	if first.QueueConfig == nil || first.QueueConfig.MaxShards != 10 {
+		t.Errorf("unexpected queue config %v", first.QueueConfig)
+	}
+
+	// Basic auth credentials are read from the Secret by the Prometheus
+	// Operator, the other credentials are mounted.
+	if first.BasicAuth == nil || first.BasicAuth.Username.Name != "remote-write-auth" || first.BasicAuth.Password.Key != "password" {
+		t.Errorf("unexpected basic auth %v", first.BasicAuth)
+	}
+	if first.BearerToken != "" || second.BearerToken != "" {
+		t.Error("expected no inline bearer token")
+	}
+	if expected := "/etc/prometheus/secrets/remote-write-token/token"; second.BearerTokenFile != expected {
+		t.Errorf("expected bearer token file %q, got %q", expected, second.BearerTokenFile)
+	}
+	if expected := "/etc/prometheus/secrets/remote-write-tls/ca.crt"; first.TLSConfig == nil || first.TLSConfig.CAFile != expected || first.TLSConfig.ServerName != "remote.example.com" || first.TLSConfig.CertFile != "" {
+		t.Errorf("unexpected TLS config %v", first.TLSConfig)
+	}
+	if expected := "/etc/prometheus/secrets/remote-write-tls/tls.key"; second.TLSConfig == nil || second.TLSConfig.KeyFile != expected {
+		t.Errorf("unexpected TLS config %v", second.TLSConfig)
+	}
+
+	mounted := 0
+	for _, s := range p.Spec.Secrets {
+		switch s {
+		case "remote-write-tls", "remote-write-token":
+			mounted++
+		case "remote-write-auth":
+			t.Error("expected the basic auth Secret not to be mounted")
+		}
+	}
+	if mounted != 2 {
+		t.Errorf("expected the TLS and token Secrets to be mounted once each, got %v", p.Spec.Secrets)
+	}
+
+	if got, expected := c.RemoteWriteSecrets(), []string{"remote-write-auth", "remote-write-tls", "remote-write-token"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected referenced Secrets %v, got %v", expected, got)
+	}
+}
+
+// managedResources are the resources of ManagedKinds, as referenced by the
+// rules of roles.
+var managedResources = map[string]string{
+	"Service":                    "services",
+	"Endpoints":                  "endpoints",
+	"Secret":                     "secrets",
+	"ConfigMap":                  "configmaps",
+	"ServiceAccount":             "serviceaccounts",
+	"Deployment":                 "deployments",
+	"DaemonSet":                  "daemonsets",
+	"Role":                       "roles",
+	"RoleBinding":                "rolebindings",
+	"ClusterRole":                "clusterroles",
+	"ClusterRoleBinding":         "clusterrolebindings",
+	monv1.PrometheusesKind:       "prometheuses",
+	monv1.AlertmanagersKind:      "alertmanagers",
+	monv1.ServiceMonitorsKind:    "servicemonitors",
+	monv1.PrometheusRuleKind:     "prometheusrules",
+	"Route":                      "routes",
+	"SecurityContextConstraints": "securitycontextconstraints",
+	"Ingress":                    "ingresses",
+}
+
+// allows returns true if one of the rules of the role grants the verb on
+// the resource of the group.
+func allows(role *rbacv1beta1.ClusterRole, group, resource, verb string) bool {
+	matches := func(values []string, v string) bool {
+		for _, value := range values {
+			if value == v || value == "*" {
+				return true
+			}
+		}
+		return false
+	}
+	for _, r := range role.Rules {
+		if len(r.ResourceNames) == 0 && matches(r.APIGroups, group) && matches(r.Resources, resource) && matches(r.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOperatorRoleCoversManagedKinds(t *testing.T) {
+	b, err := ioutil.ReadFile("../../manifests/cluster-monitoring-operator-role.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	role := &rbacv1beta1.ClusterRole{}
+	if err := yaml.Unmarshal(b, role); err != nil {
+		t.Fatal(err)
+	}
+
+	// Apply reads, creates, replaces and patches objects, and Prune lists
+	// and deletes them.
+	for _, gvk := range ManagedKinds {
+		resource, ok := managedResources[gvk.Kind]
+		if !ok {
+			t.Errorf("unknown resource of kind %s", gvk.Kind)
+			continue
+		}
+		for _, verb := range []string{"get", "create", "update", "patch", "list", "delete"} {
+			if !allows(role, gvk.Group, resource, verb) {
+				t.Errorf("the operator role does not allow to %s %s", verb, resource)
+			}
+		}
+	}
+}
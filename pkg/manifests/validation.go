@@ -0,0 +1,771 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+var (
+	// durationRegexp matches the durations accepted by Prometheus, such
+	// as the ones of the storage.tsdb.retention flag.
+	durationRegexp = regexp.MustCompile(`^[0-9]+(ms|s|m|h|d|w|y)$`)
+
+	// baseImageRegexp matches image repositories, optionally prefixed by
+	// a registry host, without tag or digest, as tags are set separately.
+	baseImageRegexp = regexp.MustCompile(`^(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?)*(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// minRotationPeriod keeps secrets from being rotated, and the pods using them
+// restarted, all the time.
+const minRotationPeriod = time.Hour
+
+// Validate performs semantic checks of the configuration. The paths of the
+// returned errors are the paths of the offending fields in the YAML
+// configuration.
+func (c *Config) Validate() field.ErrorList {
+	errs := field.ErrorList{}
+
+	if c.PrometheusOperatorConfig != nil {
+		p := field.NewPath("prometheusOperator")
+		errs = append(errs, validateBaseImage(p.Child("baseImage"), c.PrometheusOperatorConfig.BaseImage)...)
+		errs = append(errs, validateBaseImage(p.Child("prometheusConfigReloaderBaseImage"), c.PrometheusOperatorConfig.PrometheusConfigReloader)...)
+		errs = append(errs, validateBaseImage(p.Child("configReloaderBaseImage"), c.PrometheusOperatorConfig.ConfigReloaderImage)...)
+		errs = append(errs, validateScheduling(p, c.PrometheusOperatorConfig.Tolerations, c.PrometheusOperatorConfig.TopologySpreadConstraints)...)
+		errs = append(errs, validateContainerResources(p.Child("containerResources"), c.PrometheusOperatorConfig.ContainerResources, prometheusOperatorContainers)...)
+		errs = append(errs, validateRetryPolicy(p.Child("retryPolicy"), c.PrometheusOperatorConfig.RetryPolicy)...)
+	}
+
+	if c.PrometheusK8sConfig != nil {
+		p := field.NewPath("prometheusK8s")
+		if r := c.PrometheusK8sConfig.Retention; r != "" && !durationRegexp.MatchString(r) {
+			errs = append(errs, field.Invalid(p.Child("retention"), r, "must be a duration like 15d, 24h or 90m"))
+		}
+		errs = append(errs, validateBaseImage(p.Child("baseImage"), c.PrometheusK8sConfig.BaseImage)...)
+		errs = append(errs, validateScheduling(p, c.PrometheusK8sConfig.Tolerations, c.PrometheusK8sConfig.TopologySpreadConstraints)...)
+		errs = append(errs, validateResources(p.Child("resources"), c.PrometheusK8sConfig.Resources)...)
+		errs = append(errs, validateContainerResources(p.Child("containerResources"), c.PrometheusK8sConfig.ContainerResources, prometheusK8sContainers)...)
+		if _, ok := c.PrometheusK8sConfig.ContainerResources["prometheus"]; ok && c.PrometheusK8sConfig.Resources != nil {
+			errs = append(errs, field.Forbidden(p.Child("containerResources").Key("prometheus"), "may not be set together with resources"))
+		}
+		errs = append(errs, validateVolumeClaimTemplate(p.Child("volumeClaimTemplate"), c.PrometheusK8sConfig.VolumeClaimTemplate)...)
+		errs = append(errs, validateRemoteWrite(p.Child("remoteWrite"), c.PrometheusK8sConfig.RemoteWrite)...)
+		errs = append(errs, validateRules(p.Child("rules"), c.PrometheusK8sConfig.Rules)...)
+		errs = append(errs, validateExpose(p.Child("expose"), c.PrometheusK8sConfig.Expose)...)
+		errs = append(errs, validateRetryPolicy(p.Child("retryPolicy"), c.PrometheusK8sConfig.RetryPolicy)...)
+	}
+
+	if c.AlertmanagerMainConfig != nil {
+		p := field.NewPath("alertmanagerMain")
+		errs = append(errs, validateBaseImage(p.Child("baseImage"), c.AlertmanagerMainConfig.BaseImage)...)
+		errs = append(errs, validateScheduling(p, c.AlertmanagerMainConfig.Tolerations, c.AlertmanagerMainConfig.TopologySpreadConstraints)...)
+		errs = append(errs, validateResources(p.Child("resources"), c.AlertmanagerMainConfig.Resources)...)
+		errs = append(errs, validateContainerResources(p.Child("containerResources"), c.AlertmanagerMainConfig.ContainerResources, alertmanagerMainContainers)...)
+		if _, ok := c.AlertmanagerMainConfig.ContainerResources["alertmanager"]; ok && c.AlertmanagerMainConfig.Resources != nil {
+			errs = append(errs, field.Forbidden(p.Child("containerResources").Key("alertmanager"), "may not be set together with resources"))
+		}
+		errs = append(errs, validateVolumeClaimTemplate(p.Child("volumeClaimTemplate"), c.AlertmanagerMainConfig.VolumeClaimTemplate)...)
+		errs = append(errs, validateExpose(p.Child("expose"), c.AlertmanagerMainConfig.Expose)...)
+		errs = append(errs, validateRetryPolicy(p.Child("retryPolicy"), c.AlertmanagerMainConfig.RetryPolicy)...)
+	}
+
+	if c.GrafanaConfig != nil {
+		errs = append(errs, validateBaseImage(field.NewPath("grafana", "baseImage"), c.GrafanaConfig.BaseImage)...)
+		errs = append(errs, validateScheduling(field.NewPath("grafana"), c.GrafanaConfig.Tolerations, c.GrafanaConfig.TopologySpreadConstraints)...)
+		errs = append(errs, validateContainerResources(field.NewPath("grafana", "containerResources"), c.GrafanaConfig.ContainerResources, grafanaContainers)...)
+		errs = append(errs, validateExpose(field.NewPath("grafana", "expose"), c.GrafanaConfig.Expose)...)
+		errs = append(errs, validateRetryPolicy(field.NewPath("grafana", "retryPolicy"), c.GrafanaConfig.RetryPolicy)...)
+	}
+	if c.AuthConfig != nil {
+		errs = append(errs, validateBaseImage(field.NewPath("auth", "baseImage"), c.AuthConfig.BaseImage)...)
+	}
+	if c.NodeExporterConfig != nil {
+		errs = append(errs, validateBaseImage(field.NewPath("nodeExporter", "baseImage"), c.NodeExporterConfig.BaseImage)...)
+		errs = append(errs, validateScheduling(field.NewPath("nodeExporter"), c.NodeExporterConfig.Tolerations, c.NodeExporterConfig.TopologySpreadConstraints)...)
+		errs = append(errs, validateContainerResources(field.NewPath("nodeExporter", "containerResources"), c.NodeExporterConfig.ContainerResources, nodeExporterContainers)...)
+		errs = append(errs, validateRetryPolicy(field.NewPath("nodeExporter", "retryPolicy"), c.NodeExporterConfig.RetryPolicy)...)
+	}
+	if c.KubeStateMetricsConfig != nil {
+		errs = append(errs, validateBaseImage(field.NewPath("kubeStateMetrics", "baseImage"), c.KubeStateMetricsConfig.BaseImage)...)
+		errs = append(errs, validateScheduling(field.NewPath("kubeStateMetrics"), c.KubeStateMetricsConfig.Tolerations, c.KubeStateMetricsConfig.TopologySpreadConstraints)...)
+		errs = append(errs, validateContainerResources(field.NewPath("kubeStateMetrics", "containerResources"), c.KubeStateMetricsConfig.ContainerResources, kubeStateMetricsContainers)...)
+		errs = append(errs, validateRetryPolicy(field.NewPath("kubeStateMetrics", "retryPolicy"), c.KubeStateMetricsConfig.RetryPolicy)...)
+	}
+	if c.KubeRbacProxyConfig != nil {
+		errs = append(errs, validateBaseImage(field.NewPath("kubeRbacProxy", "baseImage"), c.KubeRbacProxyConfig.BaseImage)...)
+	}
+
+	if c.SecretRotation != nil && c.SecretRotation.Period != nil && c.SecretRotation.Period.Duration < minRotationPeriod {
+		errs = append(errs, field.Invalid(field.NewPath("secretRotation", "period"), c.SecretRotation.Period.Duration.String(), "must be at least "+minRotationPeriod.String()))
+	}
+
+	if c.EtcdConfig != nil {
+		p := field.NewPath("etcd", "targets", "ips")
+		for i, ip := range c.EtcdConfig.Targets.IPs {
+			for _, msg := range validation.IsValidIP(ip) {
+				errs = append(errs, field.Invalid(p.Index(i), ip, msg))
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateBaseImage(p *field.Path, image string) field.ErrorList {
+	if image == "" || baseImageRegexp.MatchString(image) {
+		return nil
+	}
+
+	return field.ErrorList{field.Invalid(p, image, "must be an image repository without tag or digest")}
+}
+
+var exposeTypes = []string{
+	string(ExposeRoute),
+	string(ExposeIngress),
+	string(ExposeNodePort),
+	string(ExposeLoadBalancer),
+	string(ExposeNone),
+}
+
+func validateExpose(p *field.Path, e *ExposeConfig) field.ErrorList {
+	if e == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	if e.Type != "" {
+		supported := false
+		for _, t := range exposeTypes {
+			supported = supported || string(e.Type) == t
+		}
+		if !supported {
+			errs = append(errs, field.NotSupported(p.Child("type"), e.Type, exposeTypes))
+		}
+	}
+
+	keys := make([]string, 0, len(e.Annotations))
+	for k := range e.Annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		for _, msg := range validation.IsQualifiedName(strings.ToLower(k)) {
+			errs = append(errs, field.Invalid(p.Child("annotations").Key(k), k, msg))
+		}
+	}
+
+	if e.TLSSecretName != "" {
+		if e.Type != ExposeIngress {
+			errs = append(errs, field.Invalid(p.Child("tlsSecretName"), e.TLSSecretName, "may only be set for the Ingress type"))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(e.TLSSecretName) {
+			errs = append(errs, field.Invalid(p.Child("tlsSecretName"), e.TLSSecretName, msg))
+		}
+	}
+
+	return errs
+}
+
+var (
+	tolerationOperators = []string{
+		string(v1.TolerationOpExists),
+		string(v1.TolerationOpEqual),
+	}
+	taintEffects = []string{
+		string(v1.TaintEffectNoSchedule),
+		string(v1.TaintEffectPreferNoSchedule),
+		string(v1.TaintEffectNoExecute),
+	}
+	unsatisfiableConstraintActions = []string{
+		string(ScheduleAnyway),
+	}
+)
+
+// validateScheduling validates the tolerations and topology spread
+// constraints of the component config at p.
+func validateScheduling(p *field.Path, tolerations []v1.Toleration, constraints []TopologySpreadConstraint) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, t := range tolerations {
+		tp := p.Child("tolerations").Index(i)
+		if t.Operator != "" && !containsString(tolerationOperators, string(t.Operator)) {
+			errs = append(errs, field.NotSupported(tp.Child("operator"), t.Operator, tolerationOperators))
+		}
+		if t.Operator == v1.TolerationOpExists && t.Value != "" {
+			errs = append(errs, field.Invalid(tp.Child("value"), t.Value, "must be empty for the Exists operator"))
+		}
+		if t.Effect != "" && !containsString(taintEffects, string(t.Effect)) {
+			errs = append(errs, field.NotSupported(tp.Child("effect"), t.Effect, taintEffects))
+		}
+	}
+
+	for i, c := range constraints {
+		cp := p.Child("topologySpreadConstraints").Index(i)
+		if c.MaxSkew != 0 && c.MaxSkew != 1 {
+			errs = append(errs, field.Invalid(cp.Child("maxSkew"), c.MaxSkew, "must be 1, the only skew pod anti-affinity aims at"))
+		}
+		if c.TopologyKey == "" {
+			errs = append(errs, field.Required(cp.Child("topologyKey"), ""))
+		} else {
+			for _, msg := range validation.IsQualifiedName(c.TopologyKey) {
+				errs = append(errs, field.Invalid(cp.Child("topologyKey"), c.TopologyKey, msg))
+			}
+		}
+		switch {
+		case c.WhenUnsatisfiable == "DoNotSchedule":
+			errs = append(errs, field.Invalid(cp.Child("whenUnsatisfiable"), c.WhenUnsatisfiable, "is not supported, as the constraints are approximated with pod anti-affinity, which would leave pods pending once every domain runs one of them"))
+		case c.WhenUnsatisfiable != "" && !containsString(unsatisfiableConstraintActions, string(c.WhenUnsatisfiable)):
+			errs = append(errs, field.NotSupported(cp.Child("whenUnsatisfiable"), c.WhenUnsatisfiable, unsatisfiableConstraintActions))
+		}
+		if c.LabelSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(c.LabelSelector); err != nil {
+				errs = append(errs, field.Invalid(cp.Child("labelSelector"), c.LabelSelector, err.Error()))
+			}
+		}
+	}
+
+	return errs
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+var relabelActions = []string{"replace", "keep", "drop", "hashmod", "labelmap", "labeldrop", "labelkeep"}
+
+func validateRemoteWrite(p *field.Path, specs []RemoteWriteSpec) field.ErrorList {
+	errs := field.ErrorList{}
+
+	for i, rw := range specs {
+		rp := p.Index(i)
+		errs = append(errs, validateURL(rp.Child("url"), rw.URL, true)...)
+		errs = append(errs, validateURL(rp.Child("proxyUrl"), rw.ProxyURL, false)...)
+		errs = append(errs, validateDuration(rp.Child("remoteTimeout"), rw.RemoteTimeout)...)
+
+		for j, rc := range rw.WriteRelabelConfigs {
+			cp := rp.Child("writeRelabelConfigs").Index(j)
+			// Prometheus matches actions case-insensitively, and
+			// defaults to replace.
+			action := strings.ToLower(rc.Action)
+			if action == "" {
+				action = "replace"
+			}
+			if !containsString(relabelActions, action) {
+				errs = append(errs, field.NotSupported(cp.Child("action"), rc.Action, relabelActions))
+			}
+			if _, err := regexp.Compile("^(?:" + rc.Regex + ")$"); err != nil {
+				errs = append(errs, field.Invalid(cp.Child("regex"), rc.Regex, err.Error()))
+			}
+			if (action == "replace" || action == "hashmod") && rc.TargetLabel == "" {
+				errs = append(errs, field.Required(cp.Child("targetLabel"), "required for the "+action+" action"))
+			}
+			if action == "hashmod" && rc.Modulus == 0 {
+				errs = append(errs, field.Required(cp.Child("modulus"), "required for the hashmod action"))
+			}
+		}
+
+		if q := rw.QueueConfig; q != nil {
+			qp := rp.Child("queueConfig")
+			for _, v := range []struct {
+				name  string
+				value int
+			}{
+				{"capacity", q.Capacity},
+				{"maxShards", q.MaxShards},
+				{"maxSamplesPerSend", q.MaxSamplesPerSend},
+				{"maxRetries", q.MaxRetries},
+			} {
+				if v.value < 0 {
+					errs = append(errs, field.Invalid(qp.Child(v.name), v.value, "must not be negative"))
+				}
+			}
+			errs = append(errs, validateDuration(qp.Child("batchSendDeadline"), q.BatchSendDeadline)...)
+			errs = append(errs, validateDuration(qp.Child("minBackoff"), q.MinBackoff)...)
+			errs = append(errs, validateDuration(qp.Child("maxBackoff"), q.MaxBackoff)...)
+		}
+
+		if rw.BasicAuth != nil {
+			errs = append(errs, validateSecretKeySelector(rp.Child("basicAuth", "username"), &rw.BasicAuth.Username)...)
+			errs = append(errs, validateSecretKeySelector(rp.Child("basicAuth", "password"), &rw.BasicAuth.Password)...)
+		}
+		errs = append(errs, validateSecretKeySelector(rp.Child("bearerToken"), rw.BearerToken)...)
+		if rw.BasicAuth != nil && rw.BearerToken != nil {
+			errs = append(errs, field.Forbidden(rp.Child("bearerToken"), "may not be set together with basicAuth"))
+		}
+
+		if t := rw.TLSConfig; t != nil {
+			tp := rp.Child("tlsConfig")
+			errs = append(errs, validateSecretKeySelector(tp.Child("ca"), t.CA)...)
+			errs = append(errs, validateSecretKeySelector(tp.Child("cert"), t.Cert)...)
+			errs = append(errs, validateSecretKeySelector(tp.Child("key"), t.Key)...)
+			if t.Cert != nil && t.Key == nil {
+				errs = append(errs, field.Required(tp.Child("key"), "required together with cert"))
+			}
+			if t.Key != nil && t.Cert == nil {
+				errs = append(errs, field.Required(tp.Child("cert"), "required together with key"))
+			}
+		}
+	}
+
+	return errs
+}
+
+// validateRules validates the rules of the configuration the way
+// Prometheus validates rule files, except for the expressions, which are
+// only evaluated by Prometheus.
+func validateRules(p *field.Path, rc *RulesConfig) field.ErrorList {
+	if rc == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	names := map[string]bool{}
+	for i, g := range rc.Groups {
+		gp := p.Child("groups").Index(i)
+		if g.Name == "" {
+			errs = append(errs, field.Required(gp.Child("name"), ""))
+		} else if names[g.Name] {
+			errs = append(errs, field.Duplicate(gp.Child("name"), g.Name))
+		}
+		names[g.Name] = true
+		errs = append(errs, validateDuration(gp.Child("interval"), g.Interval)...)
+
+		for j, r := range g.Rules {
+			errs = append(errs, validateRule(gp.Child("rules").Index(j), r)...)
+		}
+	}
+
+	if len(rc.DisabledAlerts) == 0 && len(rc.AlertOverrides) == 0 {
+		return errs
+	}
+
+	// Disabling or overriding alerts that do not exist, for example
+	// because they were renamed, fails, so that it is noticed.
+	defaults, err := NewPrometheusRule(MustAssetReader(PrometheusK8sRules))
+	if err != nil {
+		return append(errs, field.InternalError(p, err))
+	}
+	alertExists := func(o AlertOverride) bool {
+		for _, g := range defaults.Spec.Groups {
+			for _, r := range g.Rules {
+				if o.matches(r) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for i, a := range rc.DisabledAlerts {
+		if !alertExists(AlertOverride{Alert: a}) {
+			errs = append(errs, field.NotFound(p.Child("disabledAlerts").Index(i), a))
+		}
+	}
+
+	for i, o := range rc.AlertOverrides {
+		op := p.Child("alertOverrides").Index(i)
+		switch {
+		case o.Alert == "":
+			errs = append(errs, field.Required(op.Child("alert"), ""))
+		case !alertExists(AlertOverride{Alert: o.Alert}):
+			errs = append(errs, field.NotFound(op.Child("alert"), o.Alert))
+		case !alertExists(o):
+			errs = append(errs, field.NotFound(op.Child("matchLabels"), o.MatchLabels))
+		case containsString(rc.DisabledAlerts, o.Alert):
+			errs = append(errs, field.Forbidden(op.Child("alert"), "the alert is disabled"))
+		}
+		errs = append(errs, validateDuration(op.Child("for"), o.For)...)
+		errs = append(errs, validateLabelNames(op.Child("matchLabels"), o.MatchLabels)...)
+		errs = append(errs, validateLabelNames(op.Child("labels"), o.Labels)...)
+		errs = append(errs, validateLabelNames(op.Child("annotations"), o.Annotations)...)
+	}
+
+	return errs
+}
+
+func validateRule(p *field.Path, r monv1.Rule) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch {
+	case r.Record == "" && r.Alert == "":
+		errs = append(errs, field.Required(p, "one of record or alert is required"))
+	case r.Record != "" && r.Alert != "":
+		errs = append(errs, field.Forbidden(p.Child("alert"), "may not be set together with record"))
+	case r.Record != "":
+		if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+			errs = append(errs, field.Invalid(p.Child("record"), r.Record, "must be a valid metric name"))
+		}
+		if r.For != "" {
+			errs = append(errs, field.Forbidden(p.Child("for"), "may only be set for alerts"))
+		}
+		if len(r.Annotations) > 0 {
+			errs = append(errs, field.Forbidden(p.Child("annotations"), "may only be set for alerts"))
+		}
+	}
+
+	if strings.TrimSpace(r.Expr) == "" {
+		errs = append(errs, field.Required(p.Child("expr"), ""))
+	}
+	errs = append(errs, validateDuration(p.Child("for"), r.For)...)
+	errs = append(errs, validateLabelNames(p.Child("labels"), r.Labels)...)
+	errs = append(errs, validateLabelNames(p.Child("annotations"), r.Annotations)...)
+
+	return errs
+}
+
+// validateLabelNames validates that the keys of m are valid Prometheus label
+// names.
+func validateLabelNames(p *field.Path, m map[string]string) field.ErrorList {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	errs := field.ErrorList{}
+	for _, k := range keys {
+		if !model.LabelName(k).IsValid() {
+			errs = append(errs, field.Invalid(p.Key(k), k, "must be a valid label name"))
+		}
+	}
+	return errs
+}
+
+// validateURL validates an absolute HTTP or HTTPS URL.
+func validateURL(p *field.Path, value string, required bool) field.ErrorList {
+	if value == "" {
+		if required {
+			return field.ErrorList{field.Required(p, "")}
+		}
+		return nil
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return field.ErrorList{field.Invalid(p, value, err.Error())}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return field.ErrorList{field.Invalid(p, value, "must be an absolute http or https URL")}
+	}
+	return nil
+}
+
+func validateDuration(p *field.Path, d string) field.ErrorList {
+	if d == "" || durationRegexp.MatchString(d) {
+		return nil
+	}
+	return field.ErrorList{field.Invalid(p, d, "must be a duration like 30s, 5m or 1h")}
+}
+
+// validateSecretKeySelector validates a reference to a key of a Secret in
+// the namespace of the monitoring stack. sel may be nil.
+func validateSecretKeySelector(p *field.Path, sel *v1.SecretKeySelector) field.ErrorList {
+	if sel == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	if sel.Name == "" {
+		errs = append(errs, field.Required(p.Child("name"), ""))
+	} else {
+		for _, msg := range validation.IsDNS1123Subdomain(sel.Name) {
+			errs = append(errs, field.Invalid(p.Child("name"), sel.Name, msg))
+		}
+	}
+	if sel.Key == "" {
+		errs = append(errs, field.Required(p.Child("key"), ""))
+	} else {
+		for _, msg := range validation.IsConfigMapKey(sel.Key) {
+			errs = append(errs, field.Invalid(p.Child("key"), sel.Key, msg))
+		}
+	}
+	return errs
+}
+
+func validateRetryPolicy(p *field.Path, r *RetryPolicy) field.ErrorList {
+	if r == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, d := range []struct {
+		name string
+		d    *metav1.Duration
+	}{
+		{"rolloutTimeout", r.RolloutTimeout},
+		{"pollInterval", r.PollInterval},
+		{"retryBackoff", r.RetryBackoff},
+		{"maxRetryBackoff", r.MaxRetryBackoff},
+	} {
+		if d.d != nil && d.d.Duration <= 0 {
+			errs = append(errs, field.Invalid(p.Child(d.name), d.d.Duration.String(), "must be a positive duration"))
+		}
+	}
+	if r.MaxRetries != nil && *r.MaxRetries < 0 {
+		errs = append(errs, field.Invalid(p.Child("maxRetries"), *r.MaxRetries, "must not be negative"))
+	}
+
+	return errs
+}
+
+func validateResources(p *field.Path, r *v1.ResourceRequirements) field.ErrorList {
+	if r == nil {
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	for _, name := range sortedResourceNames(r.Limits) {
+		if q := r.Limits[name]; q.Sign() < 0 {
+			errs = append(errs, field.Invalid(p.Child("limits").Key(string(name)), q.String(), "must not be negative"))
+		}
+	}
+	for _, name := range sortedResourceNames(r.Requests) {
+		q := r.Requests[name]
+		if q.Sign() < 0 {
+			errs = append(errs, field.Invalid(p.Child("requests").Key(string(name)), q.String(), "must not be negative"))
+			continue
+		}
+		if l, ok := r.Limits[name]; ok && q.Cmp(l) > 0 {
+			errs = append(errs, field.Invalid(p.Child("requests").Key(string(name)), q.String(), "must be less than or equal to the limit "+l.String()))
+		}
+	}
+
+	return errs
+}
+
+// validateContainerResources validates the resources of the containers of a
+// component by name, which must be one of the given container names.
+func validateContainerResources(p *field.Path, resources map[string]v1.ResourceRequirements, containers []string) field.ErrorList {
+	errs := field.ErrorList{}
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !containsString(containers, name) {
+			errs = append(errs, field.NotSupported(p.Key(name), name, containers))
+			continue
+		}
+		r := resources[name]
+		errs = append(errs, validateResources(p.Key(name), &r)...)
+	}
+
+	return errs
+}
+
+func validateVolumeClaimTemplate(p *field.Path, pvc *v1.PersistentVolumeClaim) field.ErrorList {
+	if pvc == nil {
+		return nil
+	}
+
+	storage, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+	if ok && storage.Sign() <= 0 {
+		return field.ErrorList{field.Invalid(p.Child("spec", "resources", "requests").Key(string(v1.ResourceStorage)), storage.String(), "must be greater than zero")}
+	}
+
+	return nil
+}
+
+func sortedResourceNames(l v1.ResourceList) []v1.ResourceName {
+	names := make([]v1.ResourceName, 0, len(l))
+	for name := range l {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// deprecatedFields are the fields that were documented once and are ignored
+// now, by path, with a hint at what replaces them. They are accepted, so that
+// configs written for earlier versions keep being applied, but warned about.
+var deprecatedFields = map[string]string{
+	"ingress":                                "expose the components with the expose config of prometheusK8s, alertmanagerMain and grafana instead",
+	"prometheusK8s.externalUrl":              "the external URL is derived from hostport",
+	"alertmanagerMain.externalUrl":           "the external URL is derived from hostport",
+	"kubeStateMetrics.addonResizerBaseImage": "kube-state-metrics is not resized by the addon-resizer anymore",
+}
+
+// deprecationWarnings returns a warning for each deprecated field set in the
+// decoded JSON value v, sorted by path.
+func deprecationWarnings(v interface{}) []string {
+	paths := make([]string, 0, len(deprecatedFields))
+	for path := range deprecatedFields {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var warnings []string
+	for _, path := range paths {
+		if !hasField(v, strings.Split(path, ".")) {
+			continue
+		}
+		warnings = append(warnings, fmt.Sprintf("%s is deprecated and ignored: %s", path, deprecatedFields[path]))
+	}
+	return warnings
+}
+
+// hasField tells whether the decoded JSON value v has the field at the path
+// made of the given names.
+func hasField(v interface{}, names []string) bool {
+	for _, name := range names {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = m[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// validateFields checks that the decoded JSON value v only has fields known
+// to the type t, and that values decoded by custom unmarshalers, such as
+// resource quantities, can be decoded. Unlike the errors of the JSON
+// decoder, the returned errors carry the paths of the offending fields.
+func validateFields(p *field.Path, v interface{}, t reflect.Type) field.ErrorList {
+	if v == nil {
+		return nil
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if reflect.PtrTo(t).Implements(jsonUnmarshalerType) {
+		b, err := json.Marshal(v)
+		if err == nil {
+			err = json.Unmarshal(b, reflect.New(t).Interface())
+		}
+		if err != nil {
+			return field.ErrorList{field.Invalid(p, v, err.Error())}
+		}
+		return nil
+	}
+
+	errs := field.ErrorList{}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for _, k := range sortedKeys(m) {
+			ft, ok := fields[k]
+			if !ok {
+				if _, deprecated := deprecatedFields[childPath(p, k).String()]; deprecated {
+					continue
+				}
+				errs = append(errs, field.Forbidden(childPath(p, k), "unknown field"))
+				continue
+			}
+			errs = append(errs, validateFields(childPath(p, k), m[k], ft)...)
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for _, k := range sortedKeys(m) {
+			errs = append(errs, validateFields(p.Key(k), m[k], t.Elem())...)
+		}
+	case reflect.Slice:
+		s, ok := v.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i := range s {
+			errs = append(errs, validateFields(p.Index(i), s[i], t.Elem())...)
+		}
+	}
+
+	return errs
+}
+
+// childPath returns the path of the field name of p, p being nil for the
+// root of the configuration.
+func childPath(p *field.Path, name string) *field.Path {
+	if p == nil {
+		return field.NewPath(name)
+	}
+	return p.Child(name)
+}
+
+// jsonFields returns the types of the fields of the struct type t by their
+// JSON name, including the fields of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for n, t := range jsonFields(ft) {
+					fields[n] = t
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
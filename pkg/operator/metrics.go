@@ -0,0 +1,126 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/workqueue"
+)
+
+var (
+	configParseFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cluster_monitoring_operator_config_parse_failures_total",
+			Help: "Total number of times the cluster monitoring config could not be parsed.",
+		},
+	)
+	lastSuccessfulSyncTimestamp = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "cluster_monitoring_operator_last_successful_sync_timestamp_seconds",
+			Help: "Unix timestamp of the last successful sync of the cluster monitoring stack.",
+		},
+	)
+	taskFailed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cluster_monitoring_operator_task_failed",
+			Help: "Whether the task failed or was skipped in the last sync it was part of, partitioned by task name.",
+		},
+		[]string{"task"},
+	)
+	prunedObjectsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "cluster_monitoring_operator_pruned_objects_total",
+			Help: "Total number of managed objects deleted because they were not desired anymore.",
+		},
+	)
+
+	workqueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "cluster_monitoring_operator_workqueue_depth",
+			Help: "Current depth of the workqueue.",
+		},
+		[]string{"queue"},
+	)
+	workqueueAddsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cluster_monitoring_operator_workqueue_adds_total",
+			Help: "Total number of adds handled by the workqueue.",
+		},
+		[]string{"queue"},
+	)
+	workqueueLatencyMicroseconds = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: "cluster_monitoring_operator_workqueue_latency_microseconds",
+			Help: "How long an item stays in the workqueue before being requested.",
+		},
+		[]string{"queue"},
+	)
+	workqueueWorkDurationMicroseconds = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name: "cluster_monitoring_operator_workqueue_work_duration_microseconds",
+			Help: "How long processing an item from the workqueue takes.",
+		},
+		[]string{"queue"},
+	)
+	workqueueRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cluster_monitoring_operator_workqueue_retries_total",
+			Help: "Total number of retries handled by the workqueue.",
+		},
+		[]string{"queue"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(
+		configParseFailuresTotal,
+		lastSuccessfulSyncTimestamp,
+		taskFailed,
+		prunedObjectsTotal,
+		workqueueDepth,
+		workqueueAddsTotal,
+		workqueueLatencyMicroseconds,
+		workqueueWorkDurationMicroseconds,
+		workqueueRetriesTotal,
+	)
+
+	// The provider has to be set before any workqueue is created, as the
+	// metrics of a queue are instantiated when the queue is.
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
+
+// workqueueMetricsProvider exposes the metrics of the operator's workqueue
+// through the Prometheus client library.
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return workqueueAddsTotal.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.SummaryMetric {
+	return workqueueLatencyMicroseconds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.SummaryMetric {
+	return workqueueWorkDurationMicroseconds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return workqueueRetriesTotal.WithLabelValues(name)
+}
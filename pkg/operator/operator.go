@@ -15,18 +15,34 @@
 package operator
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
 	"github.com/golang/glog"
+	routev1 "github.com/openshift/api/route/v1"
+	securityv1 "github.com/openshift/api/security/v1"
 	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+	appsv1 "k8s.io/api/apps/v1beta2"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/events"
+	"github.com/openshift/cluster-monitoring-operator/pkg/leaderelection"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/status"
 	"github.com/openshift/cluster-monitoring-operator/pkg/tasks"
 )
 
@@ -37,33 +53,98 @@ var (
 
 const (
 	resyncPeriod = 5 * time.Minute
+
+	// statusConfigMapName is the name of the ConfigMap the reconciliation
+	// status is written to.
+	statusConfigMapName = "cluster-monitoring-status"
+
+	// componentKeyPrefix prefixes queue keys that request a reconcile of a
+	// single component, as opposed to the key of the configuration
+	// ConfigMap, which requests a reconcile of the whole stack.
+	componentKeyPrefix = "component:"
+
+	// taskConcurrency is the maximum number of tasks run at the same time.
+	taskConcurrency = 4
+
+	reasonInvalidConfig = "InvalidConfiguration"
+	reasonTaskStarted   = "TaskStarted"
+	reasonTaskSucceeded = "TaskSucceeded"
+	reasonTaskFailed    = "TaskFailed"
+	reasonPruneFailed   = "PruneFailed"
+)
+
+// PruneMode tells whether the managed objects that are not desired anymore
+// are deleted after a successful reconciliation of the whole stack.
+type PruneMode string
+
+const (
+	PruneEnabled  PruneMode = "enabled"
+	PruneDryRun   PruneMode = "dry-run"
+	PruneDisabled PruneMode = "disabled"
 )
 
 type Operator struct {
 	namespace     string
 	configMapName string
 	tagOverrides  map[string]string
+	// retryPolicy is the default of the retry policies of the components.
+	retryPolicy manifests.RetryPolicy
+	pruneMode   PruneMode
+
+	client        *client.Client
+	status        *status.Reporter
+	events        *events.Recorder
+	leaderElector *leaderelection.LeaderElector
+
+	// lastKnownGoodConfig is the last configuration that was valid. It is
+	// only accessed by the worker.
+	lastKnownGoodConfig *manifests.Config
 
-	client *client.Client
+	// remoteWriteSecrets are the sorted names of the Secrets referenced by
+	// the remote write endpoints of Prometheus in the last synced config.
+	remoteWriteSecretsMtx sync.Mutex
+	remoteWriteSecrets    []string
 
 	appvInf cache.SharedIndexInformer
 	cmapInf cache.SharedIndexInformer
+	// secretInf watches the Secrets in the namespace of the operator, so
+	// that changed remote write credentials are picked up.
+	secretInf cache.SharedIndexInformer
+	// managedInfs watch the objects of built-in kinds managed by the
+	// operator, so that drift is reconciled without waiting for a resync.
+	managedInfs []cache.SharedIndexInformer
+	// managedCRDInfs watch the managed custom resources of the Prometheus
+	// Operator. They only sync once its CRDs are served, which the first
+	// sync takes care of, so drift of these objects is only noticed from
+	// then on, and earlier drift is reconciled by the next full sync.
+	managedCRDInfs []cache.SharedIndexInformer
 
 	queue workqueue.RateLimitingInterface
 }
 
-func New(namespace string, configMapName string, tagOverrides map[string]string) (*Operator, error) {
-	c, err := client.New(namespace, configMapName)
+// New creates a new Operator talking to the API server described by config.
+// If leaderElection is not nil, the operator only reconciles the monitoring
+// stack while it holds the leader lease described by it. retryPolicy is
+// used for the components whose configuration sets no retry policy, and
+// maxSyncBackoff caps the delay between retries of failed reconciliations.
+// pruneMode tells what happens to managed objects that are not desired
+// anymore.
+func New(config *rest.Config, namespace string, configMapName string, tagOverrides map[string]string, leaderElection *leaderelection.Config, retryPolicy manifests.RetryPolicy, maxSyncBackoff time.Duration, pruneMode PruneMode) (*Operator, error) {
+	c, err := client.New(config, namespace, configMapName)
 	if err != nil {
 		return nil, err
 	}
 
 	o := &Operator{
 		tagOverrides:  tagOverrides,
+		retryPolicy:   retryPolicy,
+		pruneMode:     pruneMode,
 		configMapName: configMapName,
 		namespace:     namespace,
 		client:        c,
-		queue:         workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "cluster-monitoring"),
+		status:        status.NewReporter(c, namespace, statusConfigMapName),
+		events:        c.EventRecorder(),
+		queue:         workqueue.NewNamedRateLimitingQueue(syncRateLimiter(maxSyncBackoff), "cluster-monitoring"),
 	}
 
 	o.cmapInf = cache.NewSharedIndexInformer(
@@ -75,11 +156,55 @@ func New(namespace string, configMapName string, tagOverrides map[string]string)
 		DeleteFunc: o.handleEvent,
 	})
 
+	o.secretInf = cache.NewSharedIndexInformer(
+		o.client.NamespaceSecretListWatch(), &v1.Secret{}, resyncPeriod, cache.Indexers{},
+	)
+	o.secretInf.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    o.handleSecret,
+		UpdateFunc: o.handleSecretUpdate,
+		DeleteFunc: o.handleSecret,
+	})
+
+	managedSelector := manifests.ManagedByLabel + "=" + manifests.ManagedByValue
+	for _, inf := range []struct {
+		lw  *cache.ListWatch
+		obj runtime.Object
+		crd bool
+	}{
+		{o.client.DeploymentListWatch(managedSelector), &appsv1.Deployment{}, false},
+		{o.client.DaemonSetListWatch(managedSelector), &appsv1.DaemonSet{}, false},
+		{o.client.ServiceListWatch(managedSelector), &v1.Service{}, false},
+		{o.client.SecretListWatch(managedSelector), &v1.Secret{}, false},
+		{o.client.ServiceMonitorListWatch(), &monv1.ServiceMonitor{}, true},
+		{o.client.PrometheusRuleListWatch(), &monv1.PrometheusRule{}, true},
+		{o.client.PrometheusListWatch(), &monv1.Prometheus{}, true},
+		{o.client.AlertmanagerListWatch(), &monv1.Alertmanager{}, true},
+	} {
+		i := cache.NewSharedIndexInformer(inf.lw, inf.obj, resyncPeriod, cache.Indexers{})
+		i.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			UpdateFunc: o.handleManagedUpdate,
+			DeleteFunc: o.handleManagedDelete,
+		})
+		if inf.crd {
+			o.managedCRDInfs = append(o.managedCRDInfs, i)
+		} else {
+			o.managedInfs = append(o.managedInfs, i)
+		}
+	}
+
+	if leaderElection != nil {
+		o.leaderElector, err = leaderelection.New(c.KubernetesInterface(), *leaderElection)
+		if err != nil {
+			return nil, errors.Wrap(err, "initializing leader election failed")
+		}
+	}
+
 	return o, nil
 }
 
-// Run the controller.
-func (o *Operator) Run(stopc <-chan struct{}) error {
+// Run runs the operator until ctx is done. Syncs in progress are aborted
+// when ctx is done or leadership is lost.
+func (o *Operator) Run(ctx context.Context) error {
 	defer o.queue.ShutDown()
 
 	errChan := make(chan error)
@@ -99,16 +224,43 @@ func (o *Operator) Run(stopc <-chan struct{}) error {
 		if err != nil {
 			return err
 		}
-	case <-stopc:
+	case <-ctx.Done():
 		return nil
 	}
 
-	go o.worker()
+	if o.leaderElector == nil {
+		o.run(ctx, ctx.Done())
+		return nil
+	}
+
+	return o.leaderElector.Run(ctx.Done(), func(stopc <-chan struct{}) {
+		o.run(ctx, stopc)
+	})
+}
+
+// run starts the informers and the worker, and blocks until stopc is closed
+// and the worker has finished its current item. The context of the sync in
+// progress is canceled when stopc is closed.
+func (o *Operator) run(ctx context.Context, stopc <-chan struct{}) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	donec := make(chan struct{})
+	go func() {
+		defer close(donec)
+		o.worker(ctx)
+	}()
 
 	go o.cmapInf.Run(stopc)
+	go o.secretInf.Run(stopc)
+	for _, inf := range append(o.managedInfs, o.managedCRDInfs...) {
+		go inf.Run(stopc)
+	}
 
 	<-stopc
-	return nil
+	cancel()
+	o.queue.ShutDown()
+	<-donec
 }
 
 func (c *Operator) keyFunc(obj interface{}) (string, bool) {
@@ -143,41 +295,179 @@ func (o *Operator) handleEvent(obj interface{}) {
 	o.enqueue(key)
 }
 
-func (o *Operator) worker() {
+// handleManagedUpdate enqueues a reconcile of the component owning a
+// managed object that was changed by someone else than its controller.
+// Resyncs and status-only updates, which leave the generation of objects
+// having one untouched, are ignored.
+func (o *Operator) handleManagedUpdate(old, cur interface{}) {
+	oldMeta, err := meta.Accessor(old)
+	if err != nil {
+		return
+	}
+	curMeta, err := meta.Accessor(cur)
+	if err != nil {
+		return
+	}
+
+	if oldMeta.GetResourceVersion() == curMeta.GetResourceVersion() {
+		return
+	}
+	if curMeta.GetGeneration() != 0 && oldMeta.GetGeneration() == curMeta.GetGeneration() {
+		return
+	}
+
+	o.enqueueComponent(curMeta)
+}
+
+// handleManagedDelete enqueues a reconcile of the component owning a deleted
+// managed object, so that it is recreated.
+func (o *Operator) handleManagedDelete(obj interface{}) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+
+	m, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+
+	o.enqueueComponent(m)
+}
+
+func (o *Operator) handleSecretUpdate(old, cur interface{}) {
+	oldMeta, err := meta.Accessor(old)
+	if err != nil {
+		return
+	}
+	curMeta, err := meta.Accessor(cur)
+	if err != nil || oldMeta.GetResourceVersion() == curMeta.GetResourceVersion() {
+		return
+	}
+
+	o.handleSecret(cur)
+}
+
+// handleSecret enqueues a reconcile of Prometheus when a Secret referenced
+// by its remote write endpoints changes, so that it uses the changed
+// credentials.
+func (o *Operator) handleSecret(obj interface{}) {
+	if d, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = d.Obj
+	}
+
+	m, err := meta.Accessor(obj)
+	if err != nil || m.GetNamespace() != o.namespace {
+		return
+	}
+
+	o.remoteWriteSecretsMtx.Lock()
+	i := sort.SearchStrings(o.remoteWriteSecrets, m.GetName())
+	referenced := i < len(o.remoteWriteSecrets) && o.remoteWriteSecrets[i] == m.GetName()
+	o.remoteWriteSecretsMtx.Unlock()
+	if !referenced {
+		return
+	}
+
+	glog.V(4).Infof("Remote write Secret %s/%s changed", m.GetNamespace(), m.GetName())
+	o.queue.Add(componentKeyPrefix + manifests.ComponentPrometheusK8s)
+}
+
+func (o *Operator) enqueueComponent(obj metav1.Object) {
+	l := obj.GetLabels()
+	if l[manifests.ManagedByLabel] != manifests.ManagedByValue {
+		return
+	}
+	component := l[manifests.ComponentLabel]
+	if component == "" {
+		return
+	}
+
+	glog.V(4).Infof("Managed object %s/%s of component %s changed", obj.GetNamespace(), obj.GetName(), component)
+	o.queue.Add(componentKeyPrefix + component)
+}
+
+// configMapRef references the ConfigMap holding the configuration, so that
+// events can be recorded about it even if it does not exist.
+func (o *Operator) configMapRef() *v1.ObjectReference {
+	return &v1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Namespace:  o.namespace,
+		Name:       o.configMapName,
+	}
+}
+
+// taskReporter reports the progress of tasks both in the status ConfigMap
+// and as events about the configuration ConfigMap.
+type taskReporter struct {
+	o *Operator
+}
+
+func (r *taskReporter) TaskStarted(name string) {
+	r.o.status.TaskStarted(name)
+	r.o.events.Eventf(r.o.configMapRef(), v1.EventTypeNormal, reasonTaskStarted, "Started task %q", name)
+}
+
+func (r *taskReporter) TaskFinished(name string, err error) {
+	r.o.status.TaskFinished(name, err)
+	if err != nil {
+		r.o.events.Eventf(r.o.configMapRef(), v1.EventTypeWarning, reasonTaskFailed, "Task %q failed: %v", name, err)
+		return
+	}
+	r.o.events.Eventf(r.o.configMapRef(), v1.EventTypeNormal, reasonTaskSucceeded, "Finished task %q", name)
+}
+
+// worker processes the queue once the informers synced, so that the first
+// sync reads the config and the remote write Secrets from a complete cache,
+// and drift of the objects of built-in kinds is not missed. The informers of
+// the custom resources of the Prometheus Operator are not waited for, as
+// they cannot sync before the first sync made their CRDs served.
+func (o *Operator) worker(ctx context.Context) {
+	infs := []cache.SharedInformer{o.cmapInf, o.secretInf}
+	for _, inf := range o.managedInfs {
+		infs = append(infs, inf)
+	}
+
 	glog.V(4).Info("Waiting for initial cache sync.")
-	waitForInformerInitialSync(o.cmapInf)
+	if !waitForInformerInitialSync(ctx.Done(), infs...) {
+		return
+	}
 	glog.V(4).Info("Initial cache sync done.")
 
-	for o.processNextWorkItem() {
+	for o.processNextWorkItem(ctx) {
 	}
 }
 
-func waitForInformerInitialSync(i ...cache.SharedInformer) {
+// waitForInformerInitialSync waits for the informers to sync and returns
+// true, or returns false if stopc is closed before.
+func waitForInformerInitialSync(stopc <-chan struct{}, i ...cache.SharedInformer) bool {
 	t := time.NewTicker(time.Second)
 	defer t.Stop()
 
 	for {
 		select {
+		case <-stopc:
+			return false
 		case <-t.C:
 			allSynced := true
 			for _, inf := range i {
 				allSynced = allSynced && inf.HasSynced()
 			}
 			if allSynced {
-				return
+				return true
 			}
 		}
 	}
 }
 
-func (o *Operator) processNextWorkItem() bool {
+func (o *Operator) processNextWorkItem(ctx context.Context) bool {
 	key, quit := o.queue.Get()
 	if quit {
 		return false
 	}
 	defer o.queue.Done(key)
 
-	err := o.sync(key.(string))
+	err := o.sync(ctx, key.(string))
 	if err == nil {
 		o.queue.Forget(key)
 		return true
@@ -206,49 +496,220 @@ func (o *Operator) enqueue(obj interface{}) {
 	o.queue.Add(key)
 }
 
-func (o *Operator) sync(key string) error {
-	config := o.Config()
+func (o *Operator) sync(ctx context.Context, key string) error {
+	// A component key only reconciles the task owning the component, any
+	// other key reconciles the whole stack.
+	component := ""
+	if strings.HasPrefix(key, componentKeyPrefix) {
+		component = strings.TrimPrefix(key, componentKeyPrefix)
+	}
+
+	config, err := o.Config()
+	if err != nil {
+		glog.Errorf("Invalid Cluster Monitoring config: %v", err)
+		o.events.Eventf(o.configMapRef(), v1.EventTypeWarning, reasonInvalidConfig, "%v", err)
+		o.status.ConfigInvalid(err)
+
+		// An invalid config blocks reconciling the stack. Components
+		// that drifted are still reconciled with the last config known
+		// to be good, if there is one. Returning nil avoids retrying,
+		// the ConfigMap being fixed triggers the next sync.
+		if component == "" || o.lastKnownGoodConfig == nil {
+			return nil
+		}
+		glog.V(4).Infof("Reconciling component %s with the last known good config.", component)
+		config = o.lastKnownGoodConfig
+	} else {
+		o.lastKnownGoodConfig = config
+	}
+
+	o.remoteWriteSecretsMtx.Lock()
+	o.remoteWriteSecrets = config.RemoteWriteSecrets()
+	o.remoteWriteSecretsMtx.Unlock()
 	config.SetTagOverrides(o.tagOverrides)
 
+	platform, err := DetectPlatform(o.client)
+	if err != nil {
+		return err
+	}
+	config.SetPlatform(platform)
+
 	factory := manifests.NewFactory(o.namespace, config)
 
-	tl := tasks.NewTaskRunner(
-		o.client,
-		[]*tasks.TaskSpec{
-			tasks.NewTaskSpec("Updating Prometheus Operator", tasks.NewPrometheusOperatorTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating Grafana", tasks.NewGrafanaTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating Prometheus-k8s", tasks.NewPrometheusTask(o.client, factory, config)),
-			tasks.NewTaskSpec("Updating Alertmanager", tasks.NewAlertmanagerTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating node-exporter", tasks.NewNodeExporterTask(o.client, factory)),
-			tasks.NewTaskSpec("Updating kube-state-metrics", tasks.NewKubeStateMetricsTask(o.client, factory)),
-		},
-	)
+	prometheusOperator := tasks.NewTaskSpec("Updating Prometheus Operator", tasks.NewPrometheusOperatorTask(o.client, factory)).
+		WithPolicy(o.taskPolicy(config.PrometheusOperatorConfig.RetryPolicy))
+	grafana := tasks.NewTaskSpec("Updating Grafana", tasks.NewGrafanaTask(o.client, factory)).
+		WithPolicy(o.taskPolicy(config.GrafanaConfig.RetryPolicy))
+	prometheusK8s := tasks.NewTaskSpec("Updating Prometheus-k8s", tasks.NewPrometheusTask(o.client, factory, config)).DependsOn(prometheusOperator, grafana).
+		WithPolicy(o.taskPolicy(config.PrometheusK8sConfig.RetryPolicy))
+	alertmanager := tasks.NewTaskSpec("Updating Alertmanager", tasks.NewAlertmanagerTask(o.client, factory)).DependsOn(prometheusOperator).
+		WithPolicy(o.taskPolicy(config.AlertmanagerMainConfig.RetryPolicy))
+
+	// The tasks creating monitoring.coreos.com objects depend on the
+	// Prometheus Operator task, which waits for their CRDs. Prometheus
+	// also reads the datasources Secret created by Grafana.
+	components := []struct {
+		component string
+		spec      *tasks.TaskSpec
+	}{
+		{manifests.ComponentPrometheusOperator, prometheusOperator},
+		{manifests.ComponentGrafana, grafana},
+		{manifests.ComponentPrometheusK8s, prometheusK8s},
+		{manifests.ComponentAlertmanager, alertmanager},
+		{manifests.ComponentNodeExporter, tasks.NewTaskSpec("Updating node-exporter", tasks.NewNodeExporterTask(o.client, factory)).DependsOn(prometheusOperator).
+			WithPolicy(o.taskPolicy(config.NodeExporterConfig.RetryPolicy))},
+		{manifests.ComponentKubeStateMetrics, tasks.NewTaskSpec("Updating kube-state-metrics", tasks.NewKubeStateMetricsTask(o.client, factory)).DependsOn(prometheusOperator).
+			WithPolicy(o.taskPolicy(config.KubeStateMetricsConfig.RetryPolicy))},
+		{manifests.ComponentClusterMonitoringOperator, tasks.NewTaskSpec("Updating Cluster Monitoring Operator", tasks.NewClusterMonitoringOperatorTask(o.client, factory)).DependsOn(prometheusOperator).
+			WithPolicy(o.taskPolicy(nil))},
+	}
 
-	return tl.RunAll()
+	specs := []*tasks.TaskSpec{}
+	for _, c := range components {
+		if component == "" || c.component == component {
+			specs = append(specs, c.spec)
+		}
+	}
+
+	// The generated secrets are rotated once the components mounting them
+	// were reconciled, whenever one of them is, so that annotating a
+	// secret to rotate it takes effect right away.
+	switch component {
+	case "", manifests.ComponentGrafana, manifests.ComponentPrometheusK8s, manifests.ComponentAlertmanager:
+		specs = append(specs, tasks.NewTaskSpec("Rotating secrets", tasks.NewSecretRotationTask(o.client, factory, config)).
+			DependsOn(grafana, prometheusK8s, alertmanager).WithPolicy(o.taskPolicy(nil)))
+	}
+
+	if len(specs) == 0 {
+		glog.V(4).Infof("No task owns component %q, nothing to reconcile.", component)
+		return nil
+	}
+
+	// A failing component must not prevent the others from being
+	// reconciled, so only the tasks depending on a failed task are
+	// skipped.
+	tl := tasks.NewTaskRunner(o.client, &taskReporter{o}, taskConcurrency, specs).ContinueOnError()
+
+	// Only a successful reconciliation of the whole stack tells which
+	// managed objects are not desired anymore.
+	applied := client.NewAppliedSet()
+
+	o.status.SyncStarted()
+	err = tl.RunAll(client.WithAppliedSet(ctx, applied))
+	for _, r := range tl.Results() {
+		v := 0.0
+		if r.Err != nil {
+			v = 1
+		}
+		taskFailed.WithLabelValues(r.Name).Set(v)
+	}
+	if err == nil && component == "" {
+		o.prune(ctx, applied)
+	}
+	o.status.SyncFinished(err)
+	if err == nil {
+		lastSuccessfulSyncTimestamp.Set(float64(time.Now().Unix()))
+	}
+
+	return err
+}
+
+// prune deletes the managed objects that were not applied by a successful
+// reconciliation of the whole stack, according to the prune mode. Failing to
+// prune does not fail the reconciliation, the objects are pruned by the next
+// one.
+func (o *Operator) prune(ctx context.Context, applied *client.AppliedSet) {
+	if o.pruneMode == PruneDisabled {
+		return
+	}
+
+	selector := manifests.ManagedByLabel + "=" + manifests.ManagedByValue
+	pruned, err := o.client.Prune(ctx, applied, manifests.ManagedKinds, selector, o.pruneMode == PruneDryRun)
+	if err != nil {
+		glog.Errorf("Pruning objects that are not desired anymore failed: %v", err)
+		o.events.Eventf(o.configMapRef(), v1.EventTypeWarning, reasonPruneFailed, "Pruning objects that are not desired anymore failed: %v", err)
+	}
+	if o.pruneMode == PruneEnabled {
+		prunedObjectsTotal.Add(float64(len(pruned)))
+	}
+}
+
+// DetectPlatform returns the optional APIs served by the API server c talks
+// to.
+func DetectPlatform(c *client.Client) (manifests.Platform, error) {
+	routes, err := c.Serves(routev1.SchemeGroupVersion.WithKind("Route"))
+	if err != nil {
+		return manifests.Platform{}, errors.Wrap(err, "detecting whether Routes are served failed")
+	}
+
+	sccs, err := c.Serves(securityv1.SchemeGroupVersion.WithKind("SecurityContextConstraints"))
+	if err != nil {
+		return manifests.Platform{}, errors.Wrap(err, "detecting whether SecurityContextConstraints are served failed")
+	}
+
+	return manifests.Platform{Routes: routes, SecurityContextConstraints: sccs}, nil
 }
 
-func (o *Operator) Config() *manifests.Config {
+// Config returns the configuration of the cluster monitoring stack, or the
+// default configuration if there is none. An error is returned if the
+// configuration is invalid.
+func (o *Operator) Config() (*manifests.Config, error) {
 	obj, exists, err := o.cmapInf.GetStore().GetByKey(o.namespace + "/" + o.configMapName)
 	if err != nil {
-		glog.V(4).Infof("An error occurred retrieving the Cluster Monitoring ConfigMap. Using defaults.")
-		return manifests.NewDefaultConfig()
+		return nil, errors.Wrap(err, "retrieving the Cluster Monitoring ConfigMap failed")
 	}
 	if !exists {
-		return manifests.NewDefaultConfig()
+		return manifests.NewDefaultConfig(), nil
 	}
 
 	cmap := obj.(*v1.ConfigMap)
 	configContent, found := cmap.Data["config.yaml"]
 	if !found {
 		glog.V(4).Infof("Cluster Monitoring ConfigMap does not contain a config. Using defaults.")
-		return manifests.NewDefaultConfig()
+		return manifests.NewDefaultConfig(), nil
 	}
 
 	c, err := manifests.NewConfigFromString(configContent)
 	if err != nil {
-		glog.V(4).Infof("Cluster Monitoring config could not be parsed. Using defaults.")
-		return manifests.NewDefaultConfig()
+		configParseFailuresTotal.Inc()
+		return nil, errors.Wrap(err, "the Cluster Monitoring config is invalid")
+	}
+	for _, w := range c.Warnings() {
+		glog.Warningf("Cluster Monitoring config: %s", w)
+	}
+
+	return c, nil
+}
+
+// taskPolicy returns the policy of a task from the retry policy of its
+// component, defaulting to the retry policy of the operator.
+func (o *Operator) taskPolicy(p *manifests.RetryPolicy) tasks.Policy {
+	rp := p.WithDefaults(o.retryPolicy)
+
+	tp := tasks.Policy{}
+	if rp.RolloutTimeout != nil {
+		tp.Wait.Timeout = rp.RolloutTimeout.Duration
+	}
+	if rp.PollInterval != nil {
+		tp.Wait.Interval = rp.PollInterval.Duration
+	}
+	if rp.MaxRetries != nil {
+		tp.MaxRetries = *rp.MaxRetries
 	}
+	if rp.RetryBackoff != nil {
+		tp.Backoff = rp.RetryBackoff.Duration
+	}
+	if rp.MaxRetryBackoff != nil {
+		tp.MaxBackoff = rp.MaxRetryBackoff.Duration
+	}
+	return tp
+}
 
-	return c
+// syncRateLimiter is like workqueue.DefaultControllerRateLimiter, but the
+// backoff of failed items is capped at maxBackoff.
+func syncRateLimiter(maxBackoff time.Duration) workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(5*time.Millisecond, maxBackoff),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(10), 100)},
+	)
 }
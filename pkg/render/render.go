@@ -0,0 +1,326 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package render renders the objects reconciled by the operator for a given
+// configuration without a cluster. The objects are rendered with the same
+// Factory methods, in the same order and under the same conditions as the
+// tasks use them.
+package render
+
+import (
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// Component holds the objects of one component of the monitoring stack.
+type Component struct {
+	Name    string
+	Objects []runtime.Object
+
+	// createOnly holds the indices of the objects that are only created
+	// if they do not exist, and never updated.
+	createOnly map[int]bool
+}
+
+// CreateOnly returns true if the i-th object is only created if it does not
+// exist, and never updated, like the generated secrets.
+func (c *Component) CreateOnly(i int) bool {
+	return c.createOnly[i]
+}
+
+// HostFunc returns the host a component is reachable at, given the object
+// exposing it: a Route, an Ingress or a Service.
+type HostFunc func(obj runtime.Object) (string, error)
+
+// SecretFunc returns the Secret of the given name in the namespace of the
+// monitoring stack.
+type SecretFunc func(name string) (*v1.Secret, error)
+
+// Render renders the objects of every component of the monitoring stack, in
+// the order in which they are reconciled.
+//
+// Values only known on a cluster are rendered as follows: the external URLs
+// of Prometheus and Alertmanager use the host returned by host or, if host is
+// nil, the host of their Route, the hash of the Secrets referenced by the
+// remote write endpoints of Prometheus is only set if secret is not nil, and
+// the generated passwords and session secrets are random. The objects are
+// rendered for the platform of config.
+func Render(namespace string, config *manifests.Config, host HostFunc, secret SecretFunc) ([]*Component, error) {
+	if host == nil {
+		host = func(obj runtime.Object) (string, error) {
+			if r, ok := obj.(*routev1.Route); ok {
+				return r.Spec.Host, nil
+			}
+			return "", nil
+		}
+	}
+
+	r := &renderer{
+		factory: manifests.NewFactory(namespace, config),
+		config:  config,
+		host:    host,
+		secret:  secret,
+	}
+
+	components := []struct {
+		name   string
+		render func(*objectList)
+	}{
+		{manifests.ComponentPrometheusOperator, r.prometheusOperator},
+		{manifests.ComponentGrafana, r.grafana},
+		{manifests.ComponentPrometheusK8s, r.prometheusK8s},
+		{manifests.ComponentAlertmanager, r.alertmanager},
+		{manifests.ComponentNodeExporter, r.nodeExporter},
+		{manifests.ComponentKubeStateMetrics, r.kubeStateMetrics},
+		{manifests.ComponentClusterMonitoringOperator, r.clusterMonitoringOperator},
+	}
+
+	res := make([]*Component, 0, len(components))
+	for _, c := range components {
+		l := &objectList{createOnly: map[int]bool{}}
+		c.render(l)
+		if l.err != nil {
+			return nil, errors.Wrapf(l.err, "rendering %s failed", c.name)
+		}
+		res = append(res, &Component{Name: c.name, Objects: l.objects, createOnly: l.createOnly})
+	}
+
+	return res, nil
+}
+
+// objectList accumulates rendered objects until the first error.
+type objectList struct {
+	objects    []runtime.Object
+	createOnly map[int]bool
+	err        error
+}
+
+func (l *objectList) add(obj runtime.Object, err error) {
+	if l.err != nil {
+		return
+	}
+	if err != nil {
+		l.err = err
+		return
+	}
+	l.objects = append(l.objects, obj)
+}
+
+// addCreateOnly adds an object that the tasks only create if it does not
+// exist.
+func (l *objectList) addCreateOnly(obj runtime.Object, err error) {
+	l.add(obj, err)
+	if l.err == nil {
+		l.createOnly[len(l.objects)-1] = true
+	}
+}
+
+type renderer struct {
+	factory *manifests.Factory
+	config  *manifests.Config
+	host    HostFunc
+	secret  SecretFunc
+
+	// grafanaUser is the user Prometheus expects Grafana to authenticate
+	// as. Its password is generated when rendering Grafana, which is
+	// rendered first.
+	grafanaUser manifests.HtpasswdUser
+}
+
+// expose renders the object exposing a component, and returns the host the
+// component is reachable at. Services exposing a component are rendered
+// with the other objects of the component.
+func (r *renderer) expose(l *objectList, component string) string {
+	obj, err := r.factory.Exposure(component)
+	if err != nil {
+		l.add(nil, err)
+		return ""
+	}
+
+	switch obj.(type) {
+	case nil:
+		return ""
+	case *routev1.Route:
+		l.addCreateOnly(obj, nil)
+	case *v1beta1.Ingress:
+		l.add(obj, nil)
+	}
+
+	host, err := r.host(obj)
+	if err != nil {
+		l.add(nil, errors.Wrapf(err, "retrieving the host of %s failed", component))
+	}
+	return host
+}
+
+func (r *renderer) prometheusOperator(l *objectList) {
+	f := r.factory
+	l.add(f.PrometheusOperatorServiceAccount())
+	l.add(f.PrometheusOperatorClusterRole())
+	l.add(f.PrometheusOperatorClusterRoleBinding())
+	l.add(f.PrometheusOperatorService())
+	l.add(f.PrometheusOperatorDeployment())
+	l.add(f.PrometheusOperatorServiceMonitor())
+}
+
+func (r *renderer) grafana(l *objectList) {
+	f := r.factory
+	l.add(f.GrafanaClusterRole())
+	l.add(f.GrafanaClusterRoleBinding())
+	r.expose(l, manifests.ComponentGrafana)
+	l.addCreateOnly(f.GrafanaProxySecret())
+	l.add(f.GrafanaConfig())
+
+	sds, err := f.GrafanaDatasources()
+	l.addCreateOnly(sds, err)
+	if err != nil {
+		return
+	}
+	d, err := manifests.ParseGrafanaDatasources(sds)
+	if err != nil {
+		l.add(nil, err)
+		return
+	}
+	r.grafanaUser = manifests.HtpasswdUser{Name: d.Datasources[0].BasicAuthUser, Password: d.Datasources[0].BasicAuthPassword}
+
+	cmdds, err := f.GrafanaDashboardDefinitions()
+	if err != nil {
+		l.add(nil, err)
+		return
+	}
+	for i := range cmdds.Items {
+		cm := &cmdds.Items[i]
+		if cm.Kind == "" {
+			cm.APIVersion = "v1"
+			cm.Kind = "ConfigMap"
+		}
+		l.add(cm, nil)
+	}
+
+	l.add(f.GrafanaDashboardSources())
+	l.add(f.GrafanaServiceAccount())
+	l.add(f.GrafanaService())
+	l.add(f.GrafanaDeployment())
+}
+
+func (r *renderer) prometheusK8s(l *objectList) {
+	f := r.factory
+	host := r.expose(l, manifests.ComponentPrometheusK8s)
+	if l.err != nil {
+		return
+	}
+
+	l.addCreateOnly(f.PrometheusK8sProxySecret())
+	l.addCreateOnly(f.PrometheusK8sHtpasswdSecret(r.grafanaUser))
+	l.add(f.PrometheusK8sServiceAccount())
+	l.add(f.PrometheusK8sClusterRole())
+	l.add(f.PrometheusK8sClusterRoleBinding())
+	l.add(f.PrometheusK8sRoleDefault())
+	l.add(f.PrometheusK8sRoleBindingDefault())
+	l.add(f.PrometheusK8sRoleConfig())
+	l.add(f.PrometheusK8sRoleKubeSystem())
+	l.add(f.PrometheusK8sRoleBindingKubeSystem())
+	l.add(f.PrometheusK8sRole())
+	l.add(f.PrometheusK8sRoleBinding())
+	l.add(f.PrometheusK8sRoleBindingConfig())
+	l.add(f.PrometheusK8sRules())
+	l.add(f.PrometheusK8sKubeletServiceMonitor())
+	l.add(f.PrometheusK8sApiserverServiceMonitor())
+	l.add(f.PrometheusK8sKubeControllersServiceMonitor())
+
+	if r.config.EtcdConfig != nil {
+		l.add(f.PrometheusK8sEtcdService())
+		if r.config.EtcdConfig.Targets.IPs != nil {
+			l.add(f.PrometheusK8sEtcdEndpoints())
+		}
+		l.add(f.PrometheusK8sEtcdServiceMonitor())
+	}
+
+	l.add(f.PrometheusK8sPrometheusServiceMonitor())
+	l.add(f.PrometheusK8sService())
+	l.add(f.KubeControllersService())
+	l.add(r.prometheus(host))
+}
+
+// prometheus renders the Prometheus object, with the hash of the remote write
+// Secrets if they can be retrieved.
+func (r *renderer) prometheus(host string) (*monv1.Prometheus, error) {
+	p, err := r.factory.PrometheusK8s(host)
+	if err != nil || r.secret == nil {
+		return p, err
+	}
+
+	var secrets []*v1.Secret
+	for _, name := range r.config.RemoteWriteSecrets() {
+		s, err := r.secret(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "retrieving remote write Secret %s failed", name)
+		}
+		secrets = append(secrets, s)
+	}
+	manifests.SetRemoteWriteSecretsHash(p, secrets)
+	return p, nil
+}
+
+func (r *renderer) alertmanager(l *objectList) {
+	f := r.factory
+	host := r.expose(l, manifests.ComponentAlertmanager)
+	if l.err != nil {
+		return
+	}
+
+	l.add(f.AlertmanagerServiceMonitor())
+	l.addCreateOnly(f.AlertmanagerConfig())
+	l.add(f.AlertmanagerClusterRole())
+	l.add(f.AlertmanagerClusterRoleBinding())
+	l.add(f.AlertmanagerServiceAccount())
+	l.addCreateOnly(f.AlertmanagerProxySecret())
+	l.add(f.AlertmanagerService())
+	l.add(f.AlertmanagerMain(host))
+}
+
+func (r *renderer) nodeExporter(l *objectList) {
+	f := r.factory
+	l.add(f.NodeExporterServiceMonitor())
+	if f.Platform().SecurityContextConstraints {
+		l.add(f.NodeExporterSecurityContextConstraints())
+	}
+	l.add(f.NodeExporterServiceAccount())
+	l.add(f.NodeExporterClusterRole())
+	l.add(f.NodeExporterClusterRoleBinding())
+	l.add(f.NodeExporterService())
+	l.add(f.NodeExporterDaemonSet())
+}
+
+func (r *renderer) kubeStateMetrics(l *objectList) {
+	f := r.factory
+	l.add(f.KubeStateMetricsServiceMonitor())
+	l.add(f.KubeStateMetricsServiceAccount())
+	l.add(f.KubeStateMetricsClusterRole())
+	l.add(f.KubeStateMetricsClusterRoleBinding())
+	l.add(f.KubeStateMetricsService())
+	l.add(f.KubeStateMetricsDeployment())
+}
+
+func (r *renderer) clusterMonitoringOperator(l *objectList) {
+	f := r.factory
+	l.add(f.ClusterMonitoringOperatorService())
+	l.add(f.ClusterMonitoringOperatorServiceMonitor())
+}
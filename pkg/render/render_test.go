@@ -0,0 +1,200 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package render
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client/fake"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"github.com/openshift/cluster-monitoring-operator/pkg/tasks"
+)
+
+func renderedNames(t *testing.T, config *manifests.Config) map[string]bool {
+	components, err := Render("openshift-monitoring", config, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]bool{}
+	for _, c := range components {
+		if len(c.Objects) == 0 {
+			t.Fatalf("component %s has no objects", c.Name)
+		}
+		for _, obj := range c.Objects {
+			kind := obj.GetObjectKind().GroupVersionKind().Kind
+			if kind == "" {
+				t.Fatalf("object of component %s has no kind", c.Name)
+			}
+			a, err := meta.Accessor(obj)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.GetLabels()[manifests.ManagedByLabel] != manifests.ManagedByValue || a.GetLabels()[manifests.ComponentLabel] == "" {
+				t.Errorf("%s %s of component %s is not labeled as managed, got labels %v", kind, a.GetName(), c.Name, a.GetLabels())
+			}
+			names[kind+"/"+a.GetName()] = true
+		}
+	}
+	return names
+}
+
+func TestRenderDefaultConfig(t *testing.T) {
+	names := renderedNames(t, manifests.NewDefaultConfig())
+
+	for _, n := range []string{"Prometheus/k8s", "Alertmanager/main", "Deployment/grafana", "DaemonSet/node-exporter"} {
+		if !names[n] {
+			t.Errorf("%s not rendered", n)
+		}
+	}
+	if names["Service/etcd"] {
+		t.Error("etcd Service rendered, even if etcd is disabled")
+	}
+}
+
+func TestRenderEtcd(t *testing.T) {
+	c, err := manifests.NewConfigFromString(`etcd: {targets: {ips: ["10.0.0.1"]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := renderedNames(t, c)
+
+	for _, n := range []string{"Service/etcd", "Endpoints/etcd", "ServiceMonitor/etcd"} {
+		if !names[n] {
+			t.Errorf("%s not rendered, even if etcd is enabled", n)
+		}
+	}
+}
+
+// TestRenderMatchesTasks checks that the objects rendered for each component
+// are the ones its task applies, with the same contents, except for the
+// objects that are only created, whose generated passwords are random.
+func TestRenderMatchesTasks(t *testing.T) {
+	for _, content := range []string{"", `prometheusK8s:
+  remoteWrite:
+  - url: https://remote-write.example.com/api/v1/write
+    bearerToken:
+      name: remote-write
+      key: token
+etcd:
+  targets:
+    ips:
+    - 10.0.0.1
+`} {
+		config, err := manifests.NewConfigFromString(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c := fake.NewClient("openshift-monitoring", &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: "remote-write"},
+			Data:       map[string][]byte{"token": []byte("secret")},
+		})
+		f := manifests.NewFactory("openshift-monitoring", config)
+		componentTasks := []struct {
+			name string
+			task tasks.Task
+		}{
+			{manifests.ComponentPrometheusOperator, tasks.NewPrometheusOperatorTask(c, f)},
+			{manifests.ComponentGrafana, tasks.NewGrafanaTask(c, f)},
+			{manifests.ComponentPrometheusK8s, tasks.NewPrometheusTask(c, f, config)},
+			{manifests.ComponentAlertmanager, tasks.NewAlertmanagerTask(c, f)},
+			{manifests.ComponentNodeExporter, tasks.NewNodeExporterTask(c, f)},
+			{manifests.ComponentKubeStateMetrics, tasks.NewKubeStateMetricsTask(c, f)},
+			{manifests.ComponentClusterMonitoringOperator, tasks.NewClusterMonitoringOperatorTask(c, f)},
+		}
+
+		applied := map[string][]string{}
+		for _, ct := range componentTasks {
+			c.ClearActions()
+			if err := ct.task.Run(context.Background()); err != nil {
+				t.Fatalf("running the task of %s failed: %v", ct.name, err)
+			}
+			for _, a := range c.Actions() {
+				if a.Verb == fake.VerbCreate {
+					applied[ct.name] = append(applied[ct.name], a.Kind+" "+a.Namespace+"/"+a.Name)
+				}
+			}
+		}
+
+		host := func(obj runtime.Object) (string, error) {
+			r, ok := obj.(*routev1.Route)
+			if !ok {
+				return "", nil
+			}
+			live := r.DeepCopy()
+			return live.Spec.Host, c.Get(context.Background(), live)
+		}
+		secret := func(name string) (*v1.Secret, error) {
+			s := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: name}}
+			return s, c.Get(context.Background(), s)
+		}
+		components, err := Render("openshift-monitoring", config, host, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, comp := range components {
+			rendered := []string{}
+			for i, obj := range comp.Objects {
+				a, err := meta.Accessor(obj)
+				if err != nil {
+					t.Fatal(err)
+				}
+				kind := obj.GetObjectKind().GroupVersionKind().Kind
+				rendered = append(rendered, kind+" "+a.GetNamespace()+"/"+a.GetName())
+				if comp.CreateOnly(i) {
+					continue
+				}
+
+				desired, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+				if err != nil {
+					t.Fatal(err)
+				}
+				live := &unstructured.Unstructured{}
+				live.SetGroupVersionKind(obj.GetObjectKind().GroupVersionKind())
+				live.SetNamespace(a.GetNamespace())
+				live.SetName(a.GetName())
+				if err := c.Get(context.Background(), live); err != nil {
+					t.Fatal(err)
+				}
+				// The fake client ignores the API versions of kinds.
+				delete(desired, "apiVersion")
+				delete(live.Object, "apiVersion")
+				delete(desired, "status")
+				delete(live.Object, "status")
+				if !reflect.DeepEqual(desired, live.Object) {
+					t.Errorf("%s: rendered %s %s differs from the applied one:\nrendered: %v\napplied:  %v", comp.Name, kind, a.GetName(), desired, live.Object)
+				}
+			}
+
+			sort.Strings(rendered)
+			sort.Strings(applied[comp.Name])
+			if !reflect.DeepEqual(rendered, applied[comp.Name]) {
+				t.Errorf("%s: rendered objects\n%v\ndiffer from the applied ones\n%v", comp.Name, rendered, applied[comp.Name])
+			}
+		}
+	}
+}
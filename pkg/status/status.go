@@ -0,0 +1,288 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"sync"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+)
+
+const (
+	// StatusKey is the key of the ConfigMap data holding the status.
+	StatusKey = "status.yaml"
+)
+
+type ConditionType string
+
+const (
+	// ConditionAvailable is true when the last run of every task succeeded.
+	ConditionAvailable ConditionType = "Available"
+	// ConditionProgressing is true while a sync is running.
+	ConditionProgressing ConditionType = "Progressing"
+	// ConditionDegraded is true when any task failed on its last run.
+	ConditionDegraded ConditionType = "Degraded"
+	// ConditionFailing is true when the last sync failed.
+	ConditionFailing ConditionType = "Failing"
+)
+
+const (
+	ReasonRunning       = "Running"
+	ReasonSucceeded     = "Succeeded"
+	ReasonFailed        = "Failed"
+	ReasonInvalidConfig = "InvalidConfiguration"
+)
+
+// Condition describes one aspect of the state of the monitoring stack.
+type Condition struct {
+	Type               ConditionType      `json:"type"`
+	Status             v1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time        `json:"lastTransitionTime"`
+	Reason             string             `json:"reason,omitempty"`
+	Message            string             `json:"message,omitempty"`
+}
+
+// TaskCondition describes the outcome of the last run of a single task. Its
+// status is True if the task succeeded, False if it failed and Unknown while
+// it is running.
+type TaskCondition struct {
+	Name               string             `json:"name"`
+	Status             v1.ConditionStatus `json:"status"`
+	LastTransitionTime metav1.Time        `json:"lastTransitionTime"`
+	Reason             string             `json:"reason,omitempty"`
+	Message            string             `json:"message,omitempty"`
+}
+
+// Status is the reconciliation status of the cluster monitoring stack.
+type Status struct {
+	Conditions []Condition     `json:"conditions"`
+	Tasks      []TaskCondition `json:"tasks"`
+}
+
+// Reporter records the reconciliation status and writes it to a ConfigMap
+// every time it changes, so that it can be inspected through the API.
+type Reporter struct {
+	client    *client.Client
+	namespace string
+	name      string
+
+	mtx    sync.Mutex
+	status *Status
+}
+
+func NewReporter(client *client.Client, namespace, name string) *Reporter {
+	return &Reporter{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+	}
+}
+
+// SyncStarted marks the beginning of a sync.
+func (r *Reporter) SyncStarted() {
+	r.update(func(s *Status, now metav1.Time) {
+		s.setCondition(ConditionProgressing, v1.ConditionTrue, ReasonRunning, "Reconciling the cluster monitoring stack.", now)
+	})
+}
+
+// SyncFinished marks the end of a sync, err being its result.
+func (r *Reporter) SyncFinished(err error) {
+	r.update(func(s *Status, now metav1.Time) {
+		if err != nil {
+			s.setCondition(ConditionProgressing, v1.ConditionFalse, ReasonFailed, "", now)
+			s.setCondition(ConditionFailing, v1.ConditionTrue, ReasonFailed, err.Error(), now)
+		} else {
+			s.setCondition(ConditionProgressing, v1.ConditionFalse, ReasonSucceeded, "", now)
+			s.setCondition(ConditionFailing, v1.ConditionFalse, ReasonSucceeded, "", now)
+		}
+		s.updateAvailability(now)
+	})
+}
+
+// ConfigInvalid records that reconciliation is blocked because the
+// configuration is invalid, err describing why.
+func (r *Reporter) ConfigInvalid(err error) {
+	r.update(func(s *Status, now metav1.Time) {
+		s.setCondition(ConditionProgressing, v1.ConditionFalse, ReasonInvalidConfig, "", now)
+		s.setCondition(ConditionFailing, v1.ConditionTrue, ReasonInvalidConfig, err.Error(), now)
+	})
+}
+
+// TaskStarted marks the task with the given name as running.
+func (r *Reporter) TaskStarted(name string) {
+	r.update(func(s *Status, now metav1.Time) {
+		s.setTaskCondition(name, v1.ConditionUnknown, ReasonRunning, "", now)
+	})
+}
+
+// TaskFinished records the result of the task with the given name.
+func (r *Reporter) TaskFinished(name string, err error) {
+	r.update(func(s *Status, now metav1.Time) {
+		if err != nil {
+			s.setTaskCondition(name, v1.ConditionFalse, ReasonFailed, err.Error(), now)
+			return
+		}
+		s.setTaskCondition(name, v1.ConditionTrue, ReasonSucceeded, "", now)
+	})
+}
+
+func (r *Reporter) update(f func(*Status, metav1.Time)) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.status == nil {
+		s, err := r.load()
+		if err != nil {
+			glog.Errorf("Loading previous status failed, starting with an empty status: %v", err)
+			s = &Status{}
+		}
+		r.status = s
+	}
+
+	f(r.status, metav1.Now())
+
+	if err := r.write(); err != nil {
+		glog.Errorf("Writing status failed: %v", err)
+	}
+}
+
+func (r *Reporter) load() (*Status, error) {
+	s := &Status{}
+	cm, err := r.client.KubernetesInterface().CoreV1().ConfigMaps(r.namespace).Get(r.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving status ConfigMap failed")
+	}
+
+	err = yaml.Unmarshal([]byte(cm.Data[StatusKey]), s)
+	return s, errors.Wrap(err, "decoding status failed")
+}
+
+func (r *Reporter) write() error {
+	b, err := yaml.Marshal(r.status)
+	if err != nil {
+		return errors.Wrap(err, "encoding status failed")
+	}
+
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      r.name,
+			Namespace: r.namespace,
+		},
+		Data: map[string]string{
+			StatusKey: string(b),
+		},
+	}
+
+	// The status is written directly, rather than with
+	// CreateOrUpdateConfigMap, so that status updates are not recorded as
+	// events about the ConfigMap.
+	cms := r.client.KubernetesInterface().CoreV1().ConfigMaps(r.namespace)
+	existing, err := cms.Get(r.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = cms.Create(cm)
+		return errors.Wrap(err, "creating status ConfigMap failed")
+	}
+	if err != nil {
+		return errors.Wrap(err, "retrieving status ConfigMap failed")
+	}
+
+	cm.ResourceVersion = existing.ResourceVersion
+	_, err = cms.Update(cm)
+	return errors.Wrap(err, "updating status ConfigMap failed")
+}
+
+func (s *Status) Condition(t ConditionType) *Condition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == t {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func (s *Status) Task(name string) *TaskCondition {
+	for i := range s.Tasks {
+		if s.Tasks[i].Name == name {
+			return &s.Tasks[i]
+		}
+	}
+	return nil
+}
+
+func (s *Status) setCondition(t ConditionType, status v1.ConditionStatus, reason, message string, now metav1.Time) {
+	c := s.Condition(t)
+	if c == nil {
+		s.Conditions = append(s.Conditions, Condition{Type: t})
+		c = &s.Conditions[len(s.Conditions)-1]
+	}
+
+	if c.Status != status {
+		c.LastTransitionTime = now
+	}
+	c.Status = status
+	c.Reason = reason
+	c.Message = message
+}
+
+func (s *Status) setTaskCondition(name string, status v1.ConditionStatus, reason, message string, now metav1.Time) {
+	c := s.Task(name)
+	if c == nil {
+		s.Tasks = append(s.Tasks, TaskCondition{Name: name})
+		c = &s.Tasks[len(s.Tasks)-1]
+	}
+
+	if c.Status != status {
+		c.LastTransitionTime = now
+	}
+	c.Status = status
+	c.Reason = reason
+	c.Message = message
+}
+
+// updateAvailability derives the Available and Degraded conditions from the
+// conditions of the individual tasks.
+func (s *Status) updateAvailability(now metav1.Time) {
+	failed := 0
+	for _, t := range s.Tasks {
+		if t.Status != v1.ConditionTrue {
+			failed++
+		}
+	}
+
+	switch {
+	case len(s.Tasks) == 0:
+		s.setCondition(ConditionAvailable, v1.ConditionUnknown, "", "", now)
+		s.setCondition(ConditionDegraded, v1.ConditionUnknown, "", "", now)
+	case failed == 0:
+		s.setCondition(ConditionAvailable, v1.ConditionTrue, ReasonSucceeded, "", now)
+		s.setCondition(ConditionDegraded, v1.ConditionFalse, ReasonSucceeded, "", now)
+	case failed == len(s.Tasks):
+		s.setCondition(ConditionAvailable, v1.ConditionFalse, ReasonFailed, "No task succeeded on its last run.", now)
+		s.setCondition(ConditionDegraded, v1.ConditionTrue, ReasonFailed, "No task succeeded on its last run.", now)
+	default:
+		s.setCondition(ConditionAvailable, v1.ConditionFalse, ReasonFailed, "Some tasks failed on their last run.", now)
+		s.setCondition(ConditionDegraded, v1.ConditionTrue, ReasonFailed, "Some tasks failed on their last run.", now)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package status
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConditionTransitionTime(t *testing.T) {
+	s := &Status{}
+	t0 := metav1.NewTime(time.Unix(0, 0))
+	t1 := metav1.NewTime(time.Unix(1, 0))
+	t2 := metav1.NewTime(time.Unix(2, 0))
+
+	s.setTaskCondition("Updating Grafana", v1.ConditionFalse, ReasonFailed, "first", t0)
+	s.setTaskCondition("Updating Grafana", v1.ConditionFalse, ReasonFailed, "second", t1)
+
+	c := s.Task("Updating Grafana")
+	if !c.LastTransitionTime.Equal(&t0) {
+		t.Fatalf("expected transition time to stay at %v, got %v", t0, c.LastTransitionTime)
+	}
+	if c.Message != "second" {
+		t.Fatalf("expected message to be updated, got %q", c.Message)
+	}
+
+	s.setTaskCondition("Updating Grafana", v1.ConditionTrue, ReasonSucceeded, "", t2)
+	c = s.Task("Updating Grafana")
+	if !c.LastTransitionTime.Equal(&t2) {
+		t.Fatalf("expected transition time to change to %v, got %v", t2, c.LastTransitionTime)
+	}
+}
+
+func TestAvailability(t *testing.T) {
+	now := metav1.Now()
+	cases := []struct {
+		name      string
+		tasks     []v1.ConditionStatus
+		available v1.ConditionStatus
+		degraded  v1.ConditionStatus
+		message   string
+	}{
+		{
+			name:      "no tasks",
+			available: v1.ConditionUnknown,
+			degraded:  v1.ConditionUnknown,
+		}, {
+			name:      "all succeeded",
+			tasks:     []v1.ConditionStatus{v1.ConditionTrue, v1.ConditionTrue},
+			available: v1.ConditionTrue,
+			degraded:  v1.ConditionFalse,
+		}, {
+			name:      "some failed",
+			tasks:     []v1.ConditionStatus{v1.ConditionTrue, v1.ConditionFalse},
+			available: v1.ConditionFalse,
+			degraded:  v1.ConditionTrue,
+			message:   "Some tasks failed on their last run.",
+		}, {
+			name:      "all failed",
+			tasks:     []v1.ConditionStatus{v1.ConditionFalse, v1.ConditionFalse},
+			available: v1.ConditionFalse,
+			degraded:  v1.ConditionTrue,
+			message:   "No task succeeded on its last run.",
+		},
+	}
+
+	for _, tc := range cases {
+		s := &Status{}
+		for i, st := range tc.tasks {
+			s.setTaskCondition(string(rune('a'+i)), st, "", "", now)
+		}
+		s.updateAvailability(now)
+
+		if got := s.Condition(ConditionAvailable).Status; got != tc.available {
+			t.Errorf("%s: expected Available to be %s, got %s", tc.name, tc.available, got)
+		}
+		if got := s.Condition(ConditionDegraded).Status; got != tc.degraded {
+			t.Errorf("%s: expected Degraded to be %s, got %s", tc.name, tc.degraded, got)
+		}
+		if got := s.Condition(ConditionDegraded).Message; got != tc.message {
+			t.Errorf("%s: expected Degraded message %q, got %q", tc.name, tc.message, got)
+		}
+	}
+}
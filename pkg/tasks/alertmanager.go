@@ -15,37 +15,29 @@
 package tasks
 
 import (
+	"context"
+
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
 )
 
 type AlertmanagerTask struct {
-	client  *client.Client
+	client  client.Interface
 	factory *manifests.Factory
 }
 
-func NewAlertmanagerTask(client *client.Client, factory *manifests.Factory) *AlertmanagerTask {
+func NewAlertmanagerTask(client client.Interface, factory *manifests.Factory) *AlertmanagerTask {
 	return &AlertmanagerTask{
 		client:  client,
 		factory: factory,
 	}
 }
 
-func (t *AlertmanagerTask) Run() error {
-	r, err := t.factory.AlertmanagerRoute()
-	if err != nil {
-		return errors.Wrap(err, "initializing Alertmanager Route failed")
-	}
-
-	err = t.client.CreateRouteIfNotExists(r)
-	if err != nil {
-		return errors.Wrap(err, "creating Alertmanager Route failed")
-	}
-
-	host, err := t.client.WaitForRouteReady(r)
+func (t *AlertmanagerTask) Run(ctx context.Context) error {
+	host, err := expose(ctx, t.client, t.factory, manifests.ComponentAlertmanager, "Alertmanager")
 	if err != nil {
-		return errors.Wrap(err, "waiting for Alertmanager Route to become ready failed")
+		return err
 	}
 
 	smam, err := t.factory.AlertmanagerServiceMonitor()
@@ -53,7 +45,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smam)
+	err = t.client.Apply(ctx, smam, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Alertmanager ServiceMonitor failed")
 	}
@@ -63,7 +55,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager configuration Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(s)
+	err = t.client.Apply(ctx, s, client.CreateOnly)
 	if err != nil {
 		return errors.Wrap(err, "creating Alertmanager configuration Secret failed")
 	}
@@ -73,7 +65,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager ClusterRole failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRole(cr)
+	err = t.client.Apply(ctx, cr, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Alertmanager ClusterRole failed")
 	}
@@ -83,7 +75,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager ClusterRoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	err = t.client.Apply(ctx, crb, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Alertmanager ClusterRoleBinding failed")
 	}
@@ -93,7 +85,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager ServiceAccount failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceAccount(sa)
+	err = t.client.Apply(ctx, sa, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Alertmanager ServiceAccount failed")
 	}
@@ -103,7 +95,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager proxy Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(ps)
+	err = t.client.Apply(ctx, ps, client.CreateOnly)
 	if err != nil {
 		return errors.Wrap(err, "creating Alertmanager proxy Secret failed")
 	}
@@ -113,7 +105,7 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(svc)
+	err = t.client.Apply(ctx, svc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Alertmanager Service failed")
 	}
@@ -123,11 +115,11 @@ func (t *AlertmanagerTask) Run() error {
 		return errors.Wrap(err, "initializing Alertmanager object failed")
 	}
 
-	err = t.client.CreateOrUpdateAlertmanager(a)
+	err = t.client.Apply(ctx, a, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Alertmanager object failed")
 	}
 
-	err = t.client.WaitForAlertmanager(a)
+	err = t.client.WaitForReady(ctx, a)
 	return errors.Wrap(err, "waiting for Alertmanager object changes failed")
 }
@@ -0,0 +1,91 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	routev1 "github.com/openshift/api/route/v1"
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/api/extensions/v1beta1"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// expose exposes a component outside of the cluster as configured, and
+// returns the host it is reachable at, or an empty string if it is unknown.
+// name is the name of the component in errors.
+func expose(ctx context.Context, c client.Interface, f *manifests.Factory, component, name string) (string, error) {
+	obj, err := f.Exposure(component)
+	if err != nil {
+		return "", errors.Wrapf(err, "initializing %s exposure failed", name)
+	}
+
+	switch o := obj.(type) {
+	case *routev1.Route:
+		err = c.Apply(ctx, o, client.CreateOnly)
+		if err != nil {
+			return "", errors.Wrapf(err, "creating %s Route failed", name)
+		}
+
+		host, err := c.WaitForRouteReady(ctx, o)
+		return host, errors.Wrapf(err, "waiting for %s Route to become ready failed", name)
+	case *v1beta1.Ingress:
+		err = c.Apply(ctx, o, client.Reconcile)
+		if err != nil {
+			return "", errors.Wrapf(err, "reconciling %s Ingress failed", name)
+		}
+
+		// The Ingress is only given a host if the hostport of the
+		// component is set, which takes precedence anyway.
+		return "", nil
+	case *v1.Service:
+		err = c.Apply(ctx, o, client.ReconcileAndWait)
+		if err != nil {
+			return "", errors.Wrapf(err, "reconciling %s Service failed", name)
+		}
+		if o.Spec.Type != v1.ServiceTypeLoadBalancer {
+			// The nodes a NodePort Service is reachable on are
+			// not known.
+			return "", nil
+		}
+
+		err = c.Get(ctx, o)
+		if err != nil {
+			return "", errors.Wrapf(err, "retrieving %s Service failed", name)
+		}
+		return loadBalancerHost(o), nil
+	}
+
+	return "", nil
+}
+
+// loadBalancerHost returns the address and port of the load balancer of the
+// Service.
+func loadBalancerHost(s *v1.Service) string {
+	if len(s.Status.LoadBalancer.Ingress) == 0 || len(s.Spec.Ports) == 0 {
+		return ""
+	}
+
+	host := s.Status.LoadBalancer.Ingress[0].Hostname
+	if ip := s.Status.LoadBalancer.Ingress[0].IP; ip != "" {
+		host = ip
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(s.Spec.Ports[0].Port)))
+}
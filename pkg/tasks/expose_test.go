@@ -0,0 +1,135 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"testing"
+
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/openshift/cluster-monitoring-operator/pkg/client/fake"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPrometheusTaskExposure(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		config      string
+		performed   []string
+		externalURL string
+	}{
+		{
+			name:        "not exposed",
+			performed:   []string{"create Service openshift-monitoring/prometheus-k8s"},
+			externalURL: "",
+		}, {
+			name: "Ingress",
+			config: `prometheusK8s:
+  hostport: prometheus.example.com
+  expose:
+    type: Ingress`,
+			performed:   []string{"create Ingress openshift-monitoring/prometheus-k8s"},
+			externalURL: "https://prometheus.example.com/",
+		}, {
+			name: "LoadBalancer",
+			config: `prometheusK8s:
+  expose:
+    type: LoadBalancer`,
+			performed: []string{
+				"create Service openshift-monitoring/prometheus-k8s",
+				"wait Service openshift-monitoring/prometheus-k8s",
+				"get Service openshift-monitoring/prometheus-k8s",
+			},
+			externalURL: "https://" + fake.LoadBalancerIP + ":9091/",
+		},
+	} {
+		config, err := manifests.NewConfigFromString(tc.config)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		config.SetPlatform(manifests.KubernetesPlatform)
+		f := manifests.NewFactory("openshift-monitoring", config)
+		c := newPrometheusTaskClient(t, f)
+
+		if err := NewPrometheusTask(c, f, config).Run(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		got := actions(c)
+		for _, a := range tc.performed {
+			if !contains(got, a) {
+				t.Errorf("%s: expected %q to be performed, got %v", tc.name, a, got)
+			}
+		}
+		for _, a := range c.Actions() {
+			if a.Kind == "Route" {
+				t.Errorf("%s: unexpected action on a Route: %v", tc.name, a)
+			}
+		}
+
+		p := &monv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: "k8s"}}
+		if err := c.Get(context.Background(), p); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if p.Spec.ExternalURL != tc.externalURL {
+			t.Errorf("%s: expected external URL %q, got %q", tc.name, tc.externalURL, p.Spec.ExternalURL)
+		}
+	}
+}
+
+func TestPrometheusTaskLoadBalancerNotProvisioned(t *testing.T) {
+	config, err := manifests.NewConfigFromString(`prometheusK8s:
+  expose:
+    type: LoadBalancer`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.SetPlatform(manifests.KubernetesPlatform)
+	f := manifests.NewFactory("openshift-monitoring", config)
+	c := newPrometheusTaskClient(t, f)
+	c.SetNotReady("Service", "openshift-monitoring", "prometheus-k8s")
+
+	if err := NewPrometheusTask(c, f, config).Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := actions(c); contains(got, "create Prometheus openshift-monitoring/k8s") {
+		t.Fatalf("expected the task to stop waiting for the load balancer, got %v", got)
+	}
+}
+
+func TestNodeExporterTaskPlatform(t *testing.T) {
+	scc := "create SecurityContextConstraints node-exporter"
+
+	for _, tc := range []struct {
+		platform manifests.Platform
+		expected bool
+	}{
+		{platform: manifests.OpenShiftPlatform, expected: true},
+		{platform: manifests.KubernetesPlatform, expected: false},
+	} {
+		config := manifests.NewDefaultConfig()
+		config.SetPlatform(tc.platform)
+		f := manifests.NewFactory("openshift-monitoring", config)
+		c := fake.NewClient("openshift-monitoring")
+
+		if err := NewNodeExporterTask(c, f).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		if got := actions(c); contains(got, scc) != tc.expected {
+			t.Errorf("platform %+v: expected %q to be performed: %v, got %v", tc.platform, scc, tc.expected, got)
+		}
+	}
+}
@@ -15,30 +15,32 @@
 package tasks
 
 import (
+	"context"
+
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
 )
 
 type GrafanaTask struct {
-	client  *client.Client
+	client  client.Interface
 	factory *manifests.Factory
 }
 
-func NewGrafanaTask(client *client.Client, factory *manifests.Factory) *GrafanaTask {
+func NewGrafanaTask(client client.Interface, factory *manifests.Factory) *GrafanaTask {
 	return &GrafanaTask{
 		client:  client,
 		factory: factory,
 	}
 }
 
-func (t *GrafanaTask) Run() error {
+func (t *GrafanaTask) Run(ctx context.Context) error {
 	cr, err := t.factory.GrafanaClusterRole()
 	if err != nil {
 		return errors.Wrap(err, "initializing Grafana ClusterRole failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRole(cr)
+	err = t.client.Apply(ctx, cr, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana ClusterRole failed")
 	}
@@ -48,24 +50,14 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana ClusterRoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	err = t.client.Apply(ctx, crb, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana ClusterRoleBinding failed")
 	}
 
-	r, err := t.factory.GrafanaRoute()
-	if err != nil {
-		return errors.Wrap(err, "initializing Grafana Route failed")
-	}
-
-	err = t.client.CreateRouteIfNotExists(r)
+	_, err = expose(ctx, t.client, t.factory, manifests.ComponentGrafana, "Grafana")
 	if err != nil {
-		return errors.Wrap(err, "creating Grafana Route failed")
-	}
-
-	_, err = t.client.WaitForRouteReady(r)
-	if err != nil {
-		return errors.Wrap(err, "waiting for Grafana Route to become ready failed")
+		return err
 	}
 
 	ps, err := t.factory.GrafanaProxySecret()
@@ -73,7 +65,7 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana proxy Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(ps)
+	err = t.client.Apply(ctx, ps, client.CreateOnly)
 	if err != nil {
 		return errors.Wrap(err, "creating Grafana proxy Secret failed")
 	}
@@ -83,7 +75,7 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana Config Secret failed")
 	}
 
-	err = t.client.CreateOrUpdateSecret(smc)
+	err = t.client.Apply(ctx, smc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana Config Secret failed")
 	}
@@ -93,7 +85,7 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana Datasources Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(sds)
+	err = t.client.Apply(ctx, sds, client.CreateOnly)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana Datasources Secret failed")
 	}
@@ -103,9 +95,11 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana Dashboard Definitions ConfigMaps failed")
 	}
 
-	err = t.client.CreateOrUpdateConfigMapList(cmdds)
-	if err != nil {
-		return errors.Wrap(err, "reconciling Grafana Dashboard Definitions ConfigMaps failed")
+	for i := range cmdds.Items {
+		err = t.client.Apply(ctx, &cmdds.Items[i], client.Reconcile)
+		if err != nil {
+			return errors.Wrap(err, "reconciling Grafana Dashboard Definitions ConfigMaps failed")
+		}
 	}
 
 	cmdbs, err := t.factory.GrafanaDashboardSources()
@@ -113,7 +107,7 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana Dashboard Sources ConfigMap failed")
 	}
 
-	err = t.client.CreateOrUpdateConfigMap(cmdbs)
+	err = t.client.Apply(ctx, cmdbs, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana Dashboard Sources ConfigMap failed")
 	}
@@ -123,7 +117,7 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana ServiceAccount failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceAccount(sa)
+	err = t.client.Apply(ctx, sa, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana ServiceAccount failed")
 	}
@@ -133,7 +127,7 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(svc)
+	err = t.client.Apply(ctx, svc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Grafana Service failed")
 	}
@@ -143,6 +137,6 @@ func (t *GrafanaTask) Run() error {
 		return errors.Wrap(err, "initializing Grafana Deployment failed")
 	}
 
-	err = t.client.CreateOrUpdateDeployment(d)
+	err = t.client.Apply(ctx, d, client.ReconcileAndWait)
 	return errors.Wrap(err, "reconciling Grafana Deployment failed")
 }
@@ -15,34 +15,36 @@
 package tasks
 
 import (
+	"context"
 	"reflect"
 
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1beta2"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type KubeStateMetricsTask struct {
-	client  *client.Client
+	client  client.Interface
 	factory *manifests.Factory
 }
 
-func NewKubeStateMetricsTask(client *client.Client, factory *manifests.Factory) *KubeStateMetricsTask {
+func NewKubeStateMetricsTask(client client.Interface, factory *manifests.Factory) *KubeStateMetricsTask {
 	return &KubeStateMetricsTask{
 		client:  client,
 		factory: factory,
 	}
 }
 
-func (t *KubeStateMetricsTask) Run() error {
+func (t *KubeStateMetricsTask) Run(ctx context.Context) error {
 	smksm, err := t.factory.KubeStateMetricsServiceMonitor()
 	if err != nil {
 		return errors.Wrap(err, "initializing kube-state-metrics ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smksm)
+	err = t.client.Apply(ctx, smksm, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling kube-state-metrics ServiceMonitor failed")
 	}
@@ -52,7 +54,7 @@ func (t *KubeStateMetricsTask) Run() error {
 		return errors.Wrap(err, "initializing kube-state-metrics Service failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceAccount(sa)
+	err = t.client.Apply(ctx, sa, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling kube-state-metrics ServiceAccount failed")
 	}
@@ -62,7 +64,7 @@ func (t *KubeStateMetricsTask) Run() error {
 		return errors.Wrap(err, "initializing kube-state-metrics ClusterRole failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRole(cr)
+	err = t.client.Apply(ctx, cr, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling kube-state-metrics ClusterRole failed")
 	}
@@ -72,7 +74,7 @@ func (t *KubeStateMetricsTask) Run() error {
 		return errors.Wrap(err, "initializing kube-state-metrics ClusterRoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	err = t.client.Apply(ctx, crb, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling kube-state-metrics ClusterRoleBinding failed")
 	}
@@ -82,21 +84,22 @@ func (t *KubeStateMetricsTask) Run() error {
 		return errors.Wrap(err, "initializing kube-state-metrics Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(svc)
+	err = t.client.Apply(ctx, svc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling kube-state-metrics Service failed")
 	}
 
-	return errors.Wrap(t.reconcileKubeStateMetricsDeployments(), "reconciling kube-state-metrics Deployment failed")
+	return errors.Wrap(t.reconcileKubeStateMetricsDeployments(ctx), "reconciling kube-state-metrics Deployment failed")
 }
 
-func (t *KubeStateMetricsTask) reconcileKubeStateMetricsDeployments() error {
+func (t *KubeStateMetricsTask) reconcileKubeStateMetricsDeployments(ctx context.Context) error {
 	d, err := t.factory.KubeStateMetricsDeployment()
 	if err != nil {
 		return errors.Wrap(err, "initializing kube-state-metrics Deployment for comparison failed")
 	}
 
-	depl, err := t.client.KubernetesInterface().AppsV1beta2().Deployments(d.GetNamespace()).Get(d.GetName(), metav1.GetOptions{})
+	depl := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: d.GetNamespace(), Name: d.GetName()}}
+	err = t.client.Get(ctx, depl)
 	if err != nil && !apierrors.IsNotFound(err) {
 		return errors.Wrap(err, "retrieving kube-state-metrics Deployment for comparison failed")
 	}
@@ -115,6 +118,6 @@ func (t *KubeStateMetricsTask) reconcileKubeStateMetricsDeployments() error {
 		return errors.Wrap(err, "initializing kube-state-metrics Deployment failed")
 	}
 
-	err = t.client.CreateOrUpdateDeployment(d)
+	err = t.client.Apply(ctx, d, client.ReconcileAndWait)
 	return errors.Wrap(err, "reconciling kube-state-metrics Deployment failed")
 }
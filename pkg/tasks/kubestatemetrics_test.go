@@ -0,0 +1,104 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client/fake"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+func TestKubeStateMetricsTask(t *testing.T) {
+	config := manifests.NewDefaultConfig()
+	f := manifests.NewFactory("openshift-monitoring", config)
+	c := fake.NewClient("openshift-monitoring")
+
+	if err := NewKubeStateMetricsTask(c, f).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"create ServiceMonitor openshift-monitoring/kube-state-metrics",
+		"create ServiceAccount openshift-monitoring/kube-state-metrics",
+		"create ClusterRole kube-state-metrics",
+		"create ClusterRoleBinding kube-state-metrics",
+		"create Service openshift-monitoring/kube-state-metrics",
+		"get Deployment openshift-monitoring/kube-state-metrics",
+		"create Deployment openshift-monitoring/kube-state-metrics",
+		"wait Deployment openshift-monitoring/kube-state-metrics",
+	}
+	if got := actions(c); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected actions\nexpected: %v\ngot:      %v", expected, got)
+	}
+}
+
+func TestKubeStateMetricsTaskDeployments(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		config   string
+		expected []string
+	}{
+		{
+			name:   "unchanged Deployment",
+			config: ``,
+			expected: []string{
+				"get Deployment openshift-monitoring/kube-state-metrics",
+			},
+		}, {
+			name: "changed Deployment",
+			config: `kubeStateMetrics:
+  nodeSelector:
+    type: infra`,
+			expected: []string{
+				"get Deployment openshift-monitoring/kube-state-metrics",
+				"update Deployment openshift-monitoring/kube-state-metrics",
+				"wait Deployment openshift-monitoring/kube-state-metrics",
+			},
+		},
+	} {
+		c := fake.NewClient("openshift-monitoring")
+		f := manifests.NewFactory("openshift-monitoring", manifests.NewDefaultConfig())
+		if err := NewKubeStateMetricsTask(c, f).reconcileKubeStateMetricsDeployments(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		c.ClearActions()
+
+		config, err := manifests.NewConfigFromString(tc.config)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		f = manifests.NewFactory("openshift-monitoring", config)
+		if err := NewKubeStateMetricsTask(c, f).reconcileKubeStateMetricsDeployments(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if got := actions(c); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: unexpected actions\nexpected: %v\ngot:      %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestKubeStateMetricsTaskRolloutFails(t *testing.T) {
+	f := manifests.NewFactory("openshift-monitoring", manifests.NewDefaultConfig())
+	c := fake.NewClient("openshift-monitoring")
+	c.SetNotReady("Deployment", "openshift-monitoring", "kube-state-metrics")
+
+	if err := NewKubeStateMetricsTask(c, f).Run(context.Background()); err == nil {
+		t.Fatal("expected an error when the Deployment does not roll out")
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	taskRunsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cluster_monitoring_operator_task_runs_total",
+			Help: "Total number of task runs, partitioned by task name and result.",
+		},
+		[]string{"task", "result"},
+	)
+	taskDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "cluster_monitoring_operator_task_duration_seconds",
+			Help:    "Duration of task runs in seconds, partitioned by task name.",
+			Buckets: []float64{0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+		},
+		[]string{"task"},
+	)
+	taskRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cluster_monitoring_operator_task_retries_total",
+			Help: "Total number of retries of failed tasks, partitioned by task name.",
+		},
+		[]string{"task"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(taskRunsTotal, taskDurationSeconds, taskRetriesTotal)
+}
+
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
@@ -15,42 +15,48 @@
 package tasks
 
 import (
+	"context"
+
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
 )
 
 type NodeExporterTask struct {
-	client  *client.Client
+	client  client.Interface
 	factory *manifests.Factory
 }
 
-func NewNodeExporterTask(client *client.Client, factory *manifests.Factory) *NodeExporterTask {
+func NewNodeExporterTask(client client.Interface, factory *manifests.Factory) *NodeExporterTask {
 	return &NodeExporterTask{
 		client:  client,
 		factory: factory,
 	}
 }
 
-func (t *NodeExporterTask) Run() error {
+func (t *NodeExporterTask) Run(ctx context.Context) error {
 	smn, err := t.factory.NodeExporterServiceMonitor()
 	if err != nil {
 		return errors.Wrap(err, "initializing node-exporter ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smn)
+	err = t.client.Apply(ctx, smn, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling node-exporter ServiceMonitor failed")
 	}
 
-	scc, err := t.factory.NodeExporterSecurityContextConstraints()
-	if err != nil {
-		return errors.Wrap(err, "initializing node-exporter SecurityContextConstraints failed")
-	}
-
-	err = t.client.CreateOrUpdateSecurityContextConstraints(scc)
-	if err != nil {
-		return errors.Wrap(err, "reconciling node-exporter SecurityContextConstraints failed")
+	// Clusters without SecurityContextConstraints do not restrict the
+	// host access of node-exporter through them.
+	if t.factory.Platform().SecurityContextConstraints {
+		scc, err := t.factory.NodeExporterSecurityContextConstraints()
+		if err != nil {
+			return errors.Wrap(err, "initializing node-exporter SecurityContextConstraints failed")
+		}
+
+		err = t.client.Apply(ctx, scc, client.Reconcile)
+		if err != nil {
+			return errors.Wrap(err, "reconciling node-exporter SecurityContextConstraints failed")
+		}
 	}
 
 	sa, err := t.factory.NodeExporterServiceAccount()
@@ -58,7 +64,7 @@ func (t *NodeExporterTask) Run() error {
 		return errors.Wrap(err, "initializing node-exporter Service failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceAccount(sa)
+	err = t.client.Apply(ctx, sa, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling node-exporter ServiceAccount failed")
 	}
@@ -68,7 +74,7 @@ func (t *NodeExporterTask) Run() error {
 		return errors.Wrap(err, "initializing node-exporter ClusterRole failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRole(cr)
+	err = t.client.Apply(ctx, cr, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling node-exporter ClusterRole failed")
 	}
@@ -78,7 +84,7 @@ func (t *NodeExporterTask) Run() error {
 		return errors.Wrap(err, "initializing node-exporter ClusterRoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	err = t.client.Apply(ctx, crb, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling node-exporter ClusterRoleBinding failed")
 	}
@@ -88,7 +94,7 @@ func (t *NodeExporterTask) Run() error {
 		return errors.Wrap(err, "initializing node-exporter Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(svc)
+	err = t.client.Apply(ctx, svc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling node-exporter Service failed")
 	}
@@ -98,6 +104,6 @@ func (t *NodeExporterTask) Run() error {
 		return errors.Wrap(err, "initializing node-exporter DaemonSet failed")
 	}
 
-	err = t.client.CreateOrUpdateDaemonSet(ds)
+	err = t.client.Apply(ctx, ds, client.ReconcileAndWait)
 	return errors.Wrap(err, "reconciling node-exporter DaemonSet failed")
 }
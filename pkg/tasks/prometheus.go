@@ -15,22 +15,23 @@
 package tasks
 
 import (
-	"encoding/json"
+	"context"
 
 	"github.com/golang/glog"
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type PrometheusTask struct {
-	client  *client.Client
+	client  client.Interface
 	factory *manifests.Factory
 	config  *manifests.Config
 }
 
-func NewPrometheusTask(client *client.Client, factory *manifests.Factory, config *manifests.Config) *PrometheusTask {
+func NewPrometheusTask(client client.Interface, factory *manifests.Factory, config *manifests.Config) *PrometheusTask {
 	return &PrometheusTask{
 		client:  client,
 		factory: factory,
@@ -38,20 +39,10 @@ func NewPrometheusTask(client *client.Client, factory *manifests.Factory, config
 	}
 }
 
-func (t *PrometheusTask) Run() error {
-	r, err := t.factory.PrometheusK8sRoute()
+func (t *PrometheusTask) Run(ctx context.Context) error {
+	host, err := expose(ctx, t.client, t.factory, manifests.ComponentPrometheusK8s, "Prometheus")
 	if err != nil {
-		return errors.Wrap(err, "initializing Prometheus Route failed")
-	}
-
-	err = t.client.CreateRouteIfNotExists(r)
-	if err != nil {
-		return errors.Wrap(err, "creating Prometheus Route failed")
-	}
-
-	host, err := t.client.WaitForRouteReady(r)
-	if err != nil {
-		return errors.Wrap(err, "waiting for Prometheus Route to become ready failed")
+		return err
 	}
 
 	ps, err := t.factory.PrometheusK8sProxySecret()
@@ -59,25 +50,28 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus proxy Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(ps)
+	err = t.client.Apply(ctx, ps, client.CreateOnly)
 	if err != nil {
 		return errors.Wrap(err, "creating Prometheus proxy Secret failed")
 	}
 
-	c := t.client.KubernetesInterface()
-	cm, err := c.CoreV1().Secrets(t.client.Namespace()).Get("grafana-datasources", metav1.GetOptions{})
+	cm := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: t.client.Namespace(), Name: "grafana-datasources"}}
+	err = t.client.Get(ctx, cm)
 	if err != nil {
 		return errors.Wrap(err, "failed to retrieve Grafana datasources config")
 	}
-	d := &manifests.GrafanaDatasources{}
-	err = json.Unmarshal(cm.Data["prometheus.yaml"], d)
+	d, err := manifests.ParseGrafanaDatasources(cm)
+	if err != nil {
+		return err
+	}
 
-	hs, err := t.factory.PrometheusK8sHtpasswdSecret(d.Datasources[0].BasicAuthPassword)
+	ds := d.Datasources[0]
+	hs, err := t.factory.PrometheusK8sHtpasswdSecret(manifests.HtpasswdUser{Name: ds.BasicAuthUser, Password: ds.BasicAuthPassword})
 	if err != nil {
 		return errors.Wrap(err, "initializing Prometheus htpasswd Secret failed")
 	}
 
-	err = t.client.CreateIfNotExistSecret(hs)
+	err = t.client.Apply(ctx, hs, client.CreateOnly)
 	if err != nil {
 		return errors.Wrap(err, "creating Prometheus htpasswd Secret failed")
 	}
@@ -87,7 +81,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus ServiceAccount failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceAccount(sa)
+	err = t.client.Apply(ctx, sa, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus ServiceAccount failed")
 	}
@@ -97,7 +91,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus ClusterRole failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRole(cr)
+	err = t.client.Apply(ctx, cr, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus ClusterRole failed")
 	}
@@ -107,7 +101,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus ClusterRoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	err = t.client.Apply(ctx, crb, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus ClusterRoleBinding failed")
 	}
@@ -117,7 +111,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Role default failed")
 	}
 
-	err = t.client.CreateOrUpdateRole(rd)
+	err = t.client.Apply(ctx, rd, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Role default failed")
 	}
@@ -127,7 +121,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus RoleBinding default failed")
 	}
 
-	err = t.client.CreateOrUpdateRoleBinding(rbd)
+	err = t.client.Apply(ctx, rbd, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus RoleBinding default failed")
 	}
@@ -137,7 +131,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Role config failed")
 	}
 
-	err = t.client.CreateOrUpdateRole(rc)
+	err = t.client.Apply(ctx, rc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Role config failed")
 	}
@@ -147,7 +141,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Role kube-system failed")
 	}
 
-	err = t.client.CreateOrUpdateRole(rks)
+	err = t.client.Apply(ctx, rks, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Role kube-system failed")
 	}
@@ -157,7 +151,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus RoleBinding kube-system failed")
 	}
 
-	err = t.client.CreateOrUpdateRoleBinding(rbks)
+	err = t.client.Apply(ctx, rbks, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus RoleBinding kube-system failed")
 	}
@@ -167,7 +161,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Role failed")
 	}
 
-	err = t.client.CreateOrUpdateRole(rts)
+	err = t.client.Apply(ctx, rts, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Role failed")
 	}
@@ -177,7 +171,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus RoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateRoleBinding(rbts)
+	err = t.client.Apply(ctx, rbts, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus RoleBinding failed")
 	}
@@ -187,7 +181,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus config RoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateRoleBinding(rbc)
+	err = t.client.Apply(ctx, rbc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus config RoleBinding failed")
 	}
@@ -197,7 +191,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus rules PrometheusRule failed")
 	}
 
-	err = t.client.CreateOrUpdatePrometheusRule(pm)
+	err = t.client.Apply(ctx, pm, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus rules PrometheusRule failed")
 	}
@@ -207,7 +201,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus kubelet ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smk)
+	err = t.client.Apply(ctx, smk, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus kubelet ServiceMonitor failed")
 	}
@@ -217,7 +211,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus apiserver ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(sma)
+	err = t.client.Apply(ctx, sma, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus apiserver ServiceMonitor failed")
 	}
@@ -227,7 +221,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus kube-controllers ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smkc)
+	err = t.client.Apply(ctx, smkc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus kube-controllers ServiceMonitor failed")
 	}
@@ -238,7 +232,7 @@ func (t *PrometheusTask) Run() error {
 			return errors.Wrap(err, "initializing etcd Service failed")
 		}
 
-		err = t.client.CreateOrUpdateService(svc)
+		err = t.client.Apply(ctx, svc, client.Reconcile)
 		if err != nil {
 			return errors.Wrap(err, "reconciling etcd Service failed")
 		}
@@ -249,7 +243,7 @@ func (t *PrometheusTask) Run() error {
 				return errors.Wrap(err, "initializing etcd Endpoints failed")
 			}
 
-			err = t.client.CreateOrUpdateEndpoints(endpoints)
+			err = t.client.Apply(ctx, endpoints, client.Reconcile)
 			if err != nil {
 				return errors.Wrap(err, "reconciling etcd Endpoints failed")
 			}
@@ -260,7 +254,7 @@ func (t *PrometheusTask) Run() error {
 			return errors.Wrap(err, "initializing Prometheus etcd ServiceMonitor failed")
 		}
 
-		err = t.client.CreateOrUpdateServiceMonitor(sme)
+		err = t.client.Apply(ctx, sme, client.Reconcile)
 		if err != nil {
 			return errors.Wrap(err, "reconciling Prometheus etcd ServiceMonitor failed")
 		}
@@ -271,7 +265,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Prometheus ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smp)
+	err = t.client.Apply(ctx, smp, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Prometheus ServiceMonitor failed")
 	}
@@ -281,7 +275,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(svc)
+	err = t.client.Apply(ctx, svc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Service failed")
 	}
@@ -291,7 +285,7 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing kube-controllers Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(kcmsvc)
+	err = t.client.Apply(ctx, kcmsvc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling kube-controllers Service failed")
 	}
@@ -302,13 +296,33 @@ func (t *PrometheusTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus object failed")
 	}
 
+	secrets, err := t.remoteWriteSecrets(ctx)
+	if err != nil {
+		return err
+	}
+	manifests.SetRemoteWriteSecretsHash(p, secrets)
+
 	glog.V(4).Info("reconciling Prometheus object")
-	err = t.client.CreateOrUpdatePrometheus(p)
+	err = t.client.Apply(ctx, p, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus object failed")
 	}
 
 	glog.V(4).Info("waiting for Prometheus object changes")
-	err = t.client.WaitForPrometheus(p)
+	err = t.client.WaitForReady(ctx, p)
 	return errors.Wrap(err, "waiting for Prometheus object changes failed")
 }
+
+// remoteWriteSecrets retrieves the Secrets referenced by the remote write
+// endpoints, sorted by name.
+func (t *PrometheusTask) remoteWriteSecrets(ctx context.Context) ([]*v1.Secret, error) {
+	var secrets []*v1.Secret
+	for _, name := range t.config.RemoteWriteSecrets() {
+		s := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: t.client.Namespace(), Name: name}}
+		if err := t.client.Get(ctx, s); err != nil {
+			return nil, errors.Wrapf(err, "retrieving remote write Secret %s failed", name)
+		}
+		secrets = append(secrets, s)
+	}
+	return secrets, nil
+}
@@ -0,0 +1,230 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	monv1 "github.com/coreos/prometheus-operator/pkg/client/monitoring/v1"
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/client/fake"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newPrometheusTaskClient returns a fake client holding the objects the
+// Prometheus task expects other tasks to have created.
+func newPrometheusTaskClient(t *testing.T, f *manifests.Factory) *fake.Client {
+	ds, err := f.GrafanaDatasources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClient("openshift-monitoring", ds)
+}
+
+func TestPrometheusTask(t *testing.T) {
+	config := manifests.NewDefaultConfig()
+	f := manifests.NewFactory("openshift-monitoring", config)
+	c := newPrometheusTaskClient(t, f)
+
+	if err := NewPrometheusTask(c, f, config).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"create Route openshift-monitoring/prometheus-k8s",
+		"wait Route openshift-monitoring/prometheus-k8s",
+		"get Route openshift-monitoring/prometheus-k8s",
+		"create Secret openshift-monitoring/prometheus-k8s-proxy",
+		"get Secret openshift-monitoring/grafana-datasources",
+		"create Secret openshift-monitoring/prometheus-k8s-htpasswd",
+		"create ServiceAccount openshift-monitoring/prometheus-k8s",
+		"create ClusterRole prometheus-k8s",
+		"create ClusterRoleBinding prometheus-k8s",
+		"create Role default/prometheus-k8s",
+		"create RoleBinding default/prometheus-k8s",
+		"create Role openshift-monitoring/prometheus-k8s-config",
+		"create Role kube-system/prometheus-k8s",
+		"create RoleBinding kube-system/prometheus-k8s",
+		"create Role openshift-monitoring/prometheus-k8s",
+		"create RoleBinding openshift-monitoring/prometheus-k8s",
+		"create RoleBinding openshift-monitoring/prometheus-k8s-config",
+		"create PrometheusRule openshift-monitoring/prometheus-k8s-rules",
+		"create ServiceMonitor openshift-monitoring/kubelet",
+		"create ServiceMonitor openshift-monitoring/kube-apiserver",
+		"create ServiceMonitor openshift-monitoring/kube-controllers",
+		"create ServiceMonitor openshift-monitoring/prometheus",
+		"create Service openshift-monitoring/prometheus-k8s",
+		"create Service kube-system/kube-controllers",
+		"create Prometheus openshift-monitoring/k8s",
+		"wait Prometheus openshift-monitoring/k8s",
+	}
+	if got := actions(c); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected actions\nexpected: %v\ngot:      %v", expected, got)
+	}
+
+	// The external URL of Prometheus is the host the Route was admitted
+	// with.
+	p := &monv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: "k8s"}}
+	if err := c.Get(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "https://prometheus-k8s-openshift-monitoring." + fake.RouterDomain + "/"; p.Spec.ExternalURL != expected {
+		t.Errorf("expected external URL %q, got %q", expected, p.Spec.ExternalURL)
+	}
+
+	// Running the task again changes nothing.
+	c.ClearActions()
+	if err := NewPrometheusTask(c, f, config).Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range c.Actions() {
+		if a.Verb == fake.VerbCreate || a.Verb == fake.VerbUpdate {
+			t.Errorf("unexpected action when nothing changed: %v", a)
+		}
+	}
+}
+
+func TestPrometheusTaskEtcd(t *testing.T) {
+	etcdActions := []string{
+		"create Service kube-system/etcd",
+		"create Endpoints kube-system/etcd",
+		"create ServiceMonitor openshift-monitoring/etcd",
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   string
+		expected []bool
+	}{
+		{
+			name:     "etcd not configured",
+			config:   ``,
+			expected: []bool{false, false, false},
+		}, {
+			name: "etcd selected by label",
+			config: `etcd:
+  targets:
+    selector:
+      openshift.io/component: etcd`,
+			expected: []bool{true, false, true},
+		}, {
+			name: "etcd selected by IP",
+			config: `etcd:
+  targets:
+    ips:
+    - 10.0.0.1`,
+			expected: []bool{true, true, true},
+		},
+	} {
+		config, err := manifests.NewConfigFromString(tc.config)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		f := manifests.NewFactory("openshift-monitoring", config)
+		c := newPrometheusTaskClient(t, f)
+
+		if err := NewPrometheusTask(c, f, config).Run(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		got := actions(c)
+		for i, a := range etcdActions {
+			if contains(got, a) != tc.expected[i] {
+				t.Errorf("%s: expected %q to be performed: %v, got %v", tc.name, a, tc.expected[i], got)
+			}
+		}
+	}
+}
+
+func TestPrometheusTaskRouteNotAdmitted(t *testing.T) {
+	config := manifests.NewDefaultConfig()
+	f := manifests.NewFactory("openshift-monitoring", config)
+	c := newPrometheusTaskClient(t, f)
+	c.SetNotReady("Route", "openshift-monitoring", "prometheus-k8s")
+
+	if err := NewPrometheusTask(c, f, config).Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	expected := []string{
+		"create Route openshift-monitoring/prometheus-k8s",
+		"wait Route openshift-monitoring/prometheus-k8s",
+	}
+	if got := actions(c); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected the task to stop waiting for the Route\nexpected: %v\ngot:      %v", expected, got)
+	}
+}
+
+func TestPrometheusTaskRemoteWriteSecrets(t *testing.T) {
+	config, err := manifests.NewConfigFromString(`prometheusK8s:
+  remoteWrite:
+  - url: https://remote.example.com/api/v1/write
+    bearerToken:
+      name: remote-write
+      key: token
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := manifests.NewFactory("openshift-monitoring", config)
+	c := newPrometheusTaskClient(t, f)
+
+	// The referenced Secret has to exist.
+	if err := NewPrometheusTask(c, f, config).Run(context.Background()); err == nil {
+		t.Fatal("expected an error for the missing Secret")
+	}
+
+	s := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: "remote-write"},
+		Data:       map[string][]byte{"token": []byte("a")},
+	}
+	hash := func() string {
+		if err := NewPrometheusTask(c, f, config).Run(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		p := &monv1.Prometheus{ObjectMeta: metav1.ObjectMeta{Namespace: "openshift-monitoring", Name: "k8s"}}
+		if err := c.Get(context.Background(), p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Spec.PodMetadata == nil {
+			t.Fatal("expected pod metadata")
+		}
+		return p.Spec.PodMetadata.Annotations[manifests.RemoteWriteSecretsHashAnnotation]
+	}
+
+	if err := c.Apply(context.Background(), s, client.Replace); err != nil {
+		t.Fatal(err)
+	}
+	first := hash()
+	if first == "" {
+		t.Fatal("expected the hash of the remote write Secrets")
+	}
+	if again := hash(); again != first {
+		t.Errorf("expected the hash to be stable, got %q and %q", first, again)
+	}
+
+	// Changed credentials change the pods of Prometheus.
+	s.Data["token"] = []byte("b")
+	if err := c.Apply(context.Background(), s, client.Replace); err != nil {
+		t.Fatal(err)
+	}
+	if changed := hash(); changed == first {
+		t.Error("expected the hash to change with the Secret")
+	}
+}
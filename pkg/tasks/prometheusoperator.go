@@ -15,30 +15,32 @@
 package tasks
 
 import (
+	"context"
+
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
 	"github.com/pkg/errors"
 )
 
 type PrometheusOperatorTask struct {
-	client  *client.Client
+	client  client.Interface
 	factory *manifests.Factory
 }
 
-func NewPrometheusOperatorTask(client *client.Client, factory *manifests.Factory) *PrometheusOperatorTask {
+func NewPrometheusOperatorTask(client client.Interface, factory *manifests.Factory) *PrometheusOperatorTask {
 	return &PrometheusOperatorTask{
 		client:  client,
 		factory: factory,
 	}
 }
 
-func (t *PrometheusOperatorTask) Run() error {
+func (t *PrometheusOperatorTask) Run(ctx context.Context) error {
 	sa, err := t.factory.PrometheusOperatorServiceAccount()
 	if err != nil {
 		return errors.Wrap(err, "initializing Prometheus Operator ServiceAccount failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceAccount(sa)
+	err = t.client.Apply(ctx, sa, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Operator ServiceAccount failed")
 	}
@@ -48,7 +50,7 @@ func (t *PrometheusOperatorTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Operator ClusterRole failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRole(cr)
+	err = t.client.Apply(ctx, cr, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Operator ClusterRole failed")
 	}
@@ -58,7 +60,7 @@ func (t *PrometheusOperatorTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Operator ClusterRoleBinding failed")
 	}
 
-	err = t.client.CreateOrUpdateClusterRoleBinding(crb)
+	err = t.client.Apply(ctx, crb, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Operator ClusterRoleBinding failed")
 	}
@@ -68,7 +70,7 @@ func (t *PrometheusOperatorTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Operator Service failed")
 	}
 
-	err = t.client.CreateOrUpdateService(svc)
+	err = t.client.Apply(ctx, svc, client.Reconcile)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Operator Service failed")
 	}
@@ -78,12 +80,12 @@ func (t *PrometheusOperatorTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Operator Deployment failed")
 	}
 
-	err = t.client.CreateOrUpdateDeployment(d)
+	err = t.client.Apply(ctx, d, client.ReconcileAndWait)
 	if err != nil {
 		return errors.Wrap(err, "reconciling Prometheus Operator Deployment failed")
 	}
 
-	err = t.client.WaitForPrometheusOperatorCRDsReady()
+	err = t.client.WaitForPrometheusOperatorCRDsReady(ctx)
 	if err != nil {
 		return errors.Wrap(err, "waiting for Prometheus CRDs to become available failed")
 	}
@@ -93,6 +95,6 @@ func (t *PrometheusOperatorTask) Run() error {
 		return errors.Wrap(err, "initializing Prometheus Operator ServiceMonitor failed")
 	}
 
-	err = t.client.CreateOrUpdateServiceMonitor(smpo)
+	err = t.client.Apply(ctx, smpo, client.Reconcile)
 	return errors.Wrap(err, "reconciling Prometheus Operator ServiceMonitor failed")
 }
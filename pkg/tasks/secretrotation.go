@@ -0,0 +1,242 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+)
+
+// SecretRotationTask regenerates the secrets generated by the other tasks
+// once they are older than the rotation period, or when they are annotated
+// with manifests.RotateAnnotation, and restarts the pods using them. It
+// depends on the tasks creating the secrets and the workloads.
+type SecretRotationTask struct {
+	client  client.Interface
+	factory *manifests.Factory
+	period  time.Duration
+	now     func() time.Time
+}
+
+func NewSecretRotationTask(client client.Interface, factory *manifests.Factory, config *manifests.Config) *SecretRotationTask {
+	t := &SecretRotationTask{
+		client:  client,
+		factory: factory,
+		now:     time.Now,
+	}
+	if config.SecretRotation != nil && config.SecretRotation.Period != nil {
+		t.period = config.SecretRotation.Period.Duration
+	}
+	return t
+}
+
+// rotation is the state of the generated secrets of a workload.
+type rotation struct {
+	name string
+	// secrets are the generated secrets mounted by the workload.
+	secrets []*v1.Secret
+	// workload returns the workload using the secrets.
+	workload func() (runtime.Object, error)
+}
+
+func (t *SecretRotationTask) Run(ctx context.Context) error {
+	grafana := &rotation{name: "Grafana", workload: func() (runtime.Object, error) { return t.factory.GrafanaDeployment() }}
+	prometheus := &rotation{name: "Prometheus", workload: func() (runtime.Object, error) { return t.factory.PrometheusK8s("") }}
+	alertmanager := &rotation{name: "Alertmanager", workload: func() (runtime.Object, error) { return t.factory.AlertmanagerMain("") }}
+
+	// The session secrets of the proxies are only read when the proxies
+	// start, so they are rotated together with the restarts of the
+	// credentials of Grafana, if any.
+	for _, p := range []struct {
+		r        *rotation
+		generate func() (*v1.Secret, error)
+	}{
+		{grafana, t.factory.GrafanaProxySecret},
+		{prometheus, t.factory.PrometheusK8sProxySecret},
+		{alertmanager, t.factory.AlertmanagerProxySecret},
+	} {
+		s, err := t.dueSecret(ctx, p.generate)
+		if err != nil {
+			return err
+		}
+		if s != nil {
+			p.r.secrets = append(p.r.secrets, s)
+		}
+	}
+
+	if err := t.rotateGrafanaCredentials(ctx, grafana, prometheus); err != nil {
+		return err
+	}
+
+	for _, r := range []*rotation{grafana, prometheus, alertmanager} {
+		if err := t.rotate(ctx, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateGrafanaCredentials rotates the password Grafana authenticates to
+// Prometheus with, if due, so that Grafana never loses access: Prometheus
+// first accepts both the current and the new password, then Grafana is
+// restarted with the new password, and finally Prometheus stops accepting
+// the old one. Otherwise it makes sure that Prometheus only accepts the
+// current password, in case a previous rotation was interrupted.
+func (t *SecretRotationTask) rotateGrafanaCredentials(ctx context.Context, grafana, prometheus *rotation) error {
+	current := &v1.Secret{}
+	if err := t.getGenerated(ctx, t.factory.GrafanaDatasources, current); err != nil {
+		return err
+	}
+	d, err := manifests.ParseGrafanaDatasources(current)
+	if err != nil {
+		return err
+	}
+	currentUser := htpasswdUser(d)
+
+	htpasswd := &v1.Secret{}
+	if err := t.getGenerated(ctx, func() (*v1.Secret, error) { return t.factory.PrometheusK8sHtpasswdSecret() }, htpasswd); err != nil {
+		return err
+	}
+
+	if !t.due(current) && !t.due(htpasswd) {
+		expected, err := t.factory.PrometheusK8sHtpasswdSecret(currentUser)
+		if err != nil {
+			return errors.Wrap(err, "initializing Prometheus htpasswd Secret failed")
+		}
+		if bytes.Equal(expected.Data["auth"], htpasswd.Data["auth"]) {
+			return nil
+		}
+		return t.rotate(ctx, withSecrets(prometheus, expected))
+	}
+
+	rotated, err := t.factory.RotatedGrafanaDatasources(d)
+	if err != nil {
+		return errors.Wrap(err, "initializing Grafana Datasources Secret failed")
+	}
+	rd, err := manifests.ParseGrafanaDatasources(rotated)
+	if err != nil {
+		return err
+	}
+	rotatedUser := htpasswdUser(rd)
+
+	both, err := t.factory.PrometheusK8sHtpasswdSecret(currentUser, rotatedUser)
+	if err != nil {
+		return errors.Wrap(err, "initializing Prometheus htpasswd Secret failed")
+	}
+	if err := t.rotate(ctx, withSecrets(prometheus, both)); err != nil {
+		return err
+	}
+
+	if err := t.rotate(ctx, withSecrets(grafana, rotated)); err != nil {
+		return err
+	}
+
+	only, err := t.factory.PrometheusK8sHtpasswdSecret(rotatedUser)
+	if err != nil {
+		return errors.Wrap(err, "initializing Prometheus htpasswd Secret failed")
+	}
+	return t.rotate(ctx, withSecrets(prometheus, only))
+}
+
+// rotate replaces the secrets of r, if any, and restarts its workload. The
+// secrets are then forgotten, so that the workload is not restarted again
+// for them.
+func (t *SecretRotationTask) rotate(ctx context.Context, r *rotation) error {
+	if len(r.secrets) == 0 {
+		return nil
+	}
+
+	for _, s := range r.secrets {
+		a := s.GetAnnotations()
+		if a == nil {
+			a = map[string]string{}
+		}
+		a[manifests.RotatedAtAnnotation] = t.now().UTC().Format(time.RFC3339)
+		s.SetAnnotations(a)
+
+		if err := t.client.Apply(ctx, s, client.Replace); err != nil {
+			return errors.Wrapf(err, "rotating Secret %s failed", s.GetName())
+		}
+	}
+	r.secrets = nil
+
+	w, err := r.workload()
+	if err != nil {
+		return errors.Wrapf(err, "initializing %s failed", r.name)
+	}
+	return errors.Wrapf(t.client.Restart(ctx, w), "restarting %s failed", r.name)
+}
+
+// withSecrets adds secrets to the secrets of r to rotate, and returns r.
+func withSecrets(r *rotation, secrets ...*v1.Secret) *rotation {
+	r.secrets = append(r.secrets, secrets...)
+	return r
+}
+
+// dueSecret returns a newly generated secret if the live secret generated
+// by generate is due for rotation, and nil otherwise.
+func (t *SecretRotationTask) dueSecret(ctx context.Context, generate func() (*v1.Secret, error)) (*v1.Secret, error) {
+	live := &v1.Secret{}
+	if err := t.getGenerated(ctx, generate, live); err != nil {
+		return nil, err
+	}
+	if !t.due(live) {
+		return nil, nil
+	}
+
+	s, err := generate()
+	return s, errors.Wrapf(err, "initializing Secret %s failed", live.GetName())
+}
+
+// getGenerated retrieves the live secret of the name and namespace of the
+// secret generated by generate into live.
+func (t *SecretRotationTask) getGenerated(ctx context.Context, generate func() (*v1.Secret, error), live *v1.Secret) error {
+	s, err := generate()
+	if err != nil {
+		return errors.Wrap(err, "initializing Secret failed")
+	}
+	live.SetNamespace(s.GetNamespace())
+	live.SetName(s.GetName())
+	return errors.Wrapf(t.client.Get(ctx, live), "retrieving Secret %s failed", s.GetName())
+}
+
+// due returns true if rotating s was requested, or if it is older than the
+// rotation period.
+func (t *SecretRotationTask) due(s *v1.Secret) bool {
+	if s.GetAnnotations()[manifests.RotateAnnotation] == "true" {
+		return true
+	}
+	if t.period == 0 {
+		return false
+	}
+
+	rotatedAt := s.GetCreationTimestamp().Time
+	if ts, err := time.Parse(time.RFC3339, s.GetAnnotations()[manifests.RotatedAtAnnotation]); err == nil {
+		rotatedAt = ts
+	}
+	return t.now().Sub(rotatedAt) >= t.period
+}
+
+func htpasswdUser(d *manifests.GrafanaDatasources) manifests.HtpasswdUser {
+	return manifests.HtpasswdUser{Name: d.Datasources[0].BasicAuthUser, Password: d.Datasources[0].BasicAuthPassword}
+}
@@ -0,0 +1,288 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client/fake"
+	"github.com/openshift/cluster-monitoring-operator/pkg/manifests"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+var rotationNow = time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// newSecretRotationTask returns a SecretRotationTask with the given config
+// and a fake client holding the generated secrets, rotated an hour ago, and
+// the workloads using them. annotate is called with the secrets before they
+// are stored.
+func newSecretRotationTask(t *testing.T, config string, annotate func(secrets map[string]*v1.Secret)) (*SecretRotationTask, *fake.Client) {
+	c, err := manifests.NewConfigFromString(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := manifests.NewFactory("openshift-monitoring", c)
+
+	ds, err := f.GrafanaDatasources()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := manifests.ParseGrafanaDatasources(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	htpasswd, err := f.PrometheusK8sHtpasswdSecret(htpasswdUser(d))
+	if err != nil {
+		t.Fatal(err)
+	}
+	secrets := map[string]*v1.Secret{ds.Name: ds, htpasswd.Name: htpasswd}
+	for _, generate := range []func() (*v1.Secret, error){f.GrafanaProxySecret, f.PrometheusK8sProxySecret, f.AlertmanagerProxySecret} {
+		s, err := generate()
+		if err != nil {
+			t.Fatal(err)
+		}
+		secrets[s.Name] = s
+	}
+	for _, s := range secrets {
+		s.Annotations = map[string]string{manifests.RotatedAtAnnotation: rotationNow.Add(-time.Hour).Format(time.RFC3339)}
+	}
+	if annotate != nil {
+		annotate(secrets)
+	}
+
+	objects := []runtime.Object{}
+	for _, s := range secrets {
+		objects = append(objects, s)
+	}
+	grafana, err := f.GrafanaDeployment()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prometheus, err := f.PrometheusK8s("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	alertmanager, err := f.AlertmanagerMain("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects = append(objects, grafana, prometheus, alertmanager)
+
+	client := fake.NewClient("openshift-monitoring", objects...)
+	task := NewSecretRotationTask(client, f, c)
+	task.now = func() time.Time { return rotationNow }
+	return task, client
+}
+
+// changes returns the actions changing objects, leaving out retrievals.
+func changes(c *fake.Client) []string {
+	res := []string{}
+	for _, a := range c.Actions() {
+		if a.Verb != fake.VerbGet {
+			res = append(res, a.String())
+		}
+	}
+	return res
+}
+
+func getSecret(t *testing.T, c *fake.Client, name string) *v1.Secret {
+	s := &v1.Secret{}
+	s.SetNamespace("openshift-monitoring")
+	s.SetName(name)
+	if err := c.Get(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestSecretRotationTask(t *testing.T) {
+	rotate := func(names ...string) func(map[string]*v1.Secret) {
+		return func(secrets map[string]*v1.Secret) {
+			for _, n := range names {
+				secrets[n].Annotations[manifests.RotateAnnotation] = "true"
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   string
+		annotate func(map[string]*v1.Secret)
+		expected []string
+	}{
+		{
+			name:     "nothing due",
+			expected: []string{},
+		}, {
+			name:     "not due within the period",
+			config:   "secretRotation:\n  period: 2h",
+			expected: []string{},
+		}, {
+			name:   "due after the period",
+			config: "secretRotation:\n  period: 2h",
+			annotate: func(secrets map[string]*v1.Secret) {
+				secrets["alertmanager-main-proxy"].Annotations[manifests.RotatedAtAnnotation] = rotationNow.Add(-3 * time.Hour).Format(time.RFC3339)
+			},
+			expected: []string{
+				"update Secret openshift-monitoring/alertmanager-main-proxy",
+				"restart Alertmanager openshift-monitoring/main",
+			},
+		}, {
+			name:   "due after the period since creation",
+			config: "secretRotation:\n  period: 2h",
+			annotate: func(secrets map[string]*v1.Secret) {
+				delete(secrets["grafana-proxy"].Annotations, manifests.RotatedAtAnnotation)
+			},
+			expected: []string{
+				"update Secret openshift-monitoring/grafana-proxy",
+				"restart Deployment openshift-monitoring/grafana",
+			},
+		}, {
+			name:     "requested",
+			annotate: rotate("prometheus-k8s-proxy"),
+			expected: []string{
+				"update Secret openshift-monitoring/prometheus-k8s-proxy",
+				"restart Prometheus openshift-monitoring/k8s",
+			},
+		}, {
+			name:     "Grafana credentials",
+			annotate: rotate("grafana-datasources"),
+			expected: []string{
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+				"update Secret openshift-monitoring/grafana-datasources",
+				"restart Deployment openshift-monitoring/grafana",
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+			},
+		}, {
+			name:     "Grafana credentials requested on the htpasswd",
+			annotate: rotate("prometheus-k8s-htpasswd"),
+			expected: []string{
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+				"update Secret openshift-monitoring/grafana-datasources",
+				"restart Deployment openshift-monitoring/grafana",
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+			},
+		}, {
+			name:     "proxies restarted with the Grafana credentials",
+			annotate: rotate("grafana-datasources", "grafana-proxy", "prometheus-k8s-proxy"),
+			expected: []string{
+				"update Secret openshift-monitoring/prometheus-k8s-proxy",
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+				"update Secret openshift-monitoring/grafana-proxy",
+				"update Secret openshift-monitoring/grafana-datasources",
+				"restart Deployment openshift-monitoring/grafana",
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+			},
+		}, {
+			name: "interrupted Grafana credentials rotation",
+			annotate: func(secrets map[string]*v1.Secret) {
+				secrets["prometheus-k8s-htpasswd"].Data["auth"] = append(secrets["prometheus-k8s-htpasswd"].Data["auth"], []byte("\ninternal-rotated:{SHA}previous")...)
+			},
+			expected: []string{
+				"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+				"restart Prometheus openshift-monitoring/k8s",
+			},
+		},
+	} {
+		task, c := newSecretRotationTask(t, tc.config, tc.annotate)
+
+		if err := task.Run(context.Background()); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got := changes(c); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("%s: unexpected actions\nexpected: %v\ngot:      %v", tc.name, tc.expected, got)
+		}
+	}
+}
+
+func TestSecretRotationTaskGrafanaCredentials(t *testing.T) {
+	task, c := newSecretRotationTask(t, "", func(secrets map[string]*v1.Secret) {
+		secrets["grafana-datasources"].Annotations[manifests.RotateAnnotation] = "true"
+	})
+	previous, err := manifests.ParseGrafanaDatasources(getSecret(t, c, "grafana-datasources"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ds := getSecret(t, c, "grafana-datasources")
+	if _, ok := ds.Annotations[manifests.RotateAnnotation]; ok {
+		t.Errorf("expected the rotation request to be removed, got annotations %v", ds.Annotations)
+	}
+	if got, expected := ds.Annotations[manifests.RotatedAtAnnotation], rotationNow.Format(time.RFC3339); got != expected {
+		t.Errorf("expected the rotation time %q, got %q", expected, got)
+	}
+
+	d, err := manifests.ParseGrafanaDatasources(ds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := d.Datasources[0].BasicAuthUser
+	if user == previous.Datasources[0].BasicAuthUser {
+		t.Errorf("expected the user to alternate, got %q again", user)
+	}
+	if d.Datasources[0].BasicAuthPassword == previous.Datasources[0].BasicAuthPassword {
+		t.Error("expected a new password")
+	}
+
+	// Prometheus only accepts the new credentials in the end.
+	auth := string(getSecret(t, c, "prometheus-k8s-htpasswd").Data["auth"])
+	if lines := strings.Split(auth, "\n"); len(lines) != 1 || !strings.HasPrefix(lines[0], user+":") {
+		t.Errorf("expected the htpasswd to only hold the user %q, got %q", user, auth)
+	}
+
+	// Running the task again changes nothing.
+	c.ClearActions()
+	if err := task.Run(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := changes(c); len(got) > 0 {
+		t.Errorf("unexpected actions when nothing is due: %v", got)
+	}
+}
+
+func TestSecretRotationTaskRestartFails(t *testing.T) {
+	task, c := newSecretRotationTask(t, "", func(secrets map[string]*v1.Secret) {
+		secrets["grafana-datasources"].Annotations[manifests.RotateAnnotation] = "true"
+	})
+	c.SetNotReady("Prometheus", "openshift-monitoring", "k8s")
+
+	if err := task.Run(context.Background()); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	// Grafana keeps its credentials until Prometheus accepts the new ones.
+	expected := []string{
+		"update Secret openshift-monitoring/prometheus-k8s-htpasswd",
+		"restart Prometheus openshift-monitoring/k8s",
+	}
+	if got := changes(c); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("unexpected actions\nexpected: %v\ngot:      %v", expected, got)
+	}
+}
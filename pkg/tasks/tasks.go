@@ -15,37 +15,280 @@
 package tasks
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/golang/glog"
 	"github.com/openshift/cluster-monitoring-operator/pkg/client"
 	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 )
 
+// StatusReporter is notified when a TaskRunner starts and finishes a task.
+type StatusReporter interface {
+	TaskStarted(name string)
+	TaskFinished(name string, err error)
+}
+
 type TaskRunner struct {
-	client *client.Client
-	tasks  []*TaskSpec
+	client          client.Interface
+	reporter        StatusReporter
+	concurrency     int
+	continueOnError bool
+	tasks           []*TaskSpec
+
+	mtx     sync.Mutex
+	results map[*TaskSpec]*TaskResult
 }
 
-func NewTaskRunner(client *client.Client, tasks []*TaskSpec) *TaskRunner {
+// TaskResult is the outcome of a task in the last RunAll.
+type TaskResult struct {
+	Name string
+	// Err is the error the task failed with, or why it was skipped.
+	Err error
+	// Skipped is true if the task was not run, because one of its
+	// dependencies failed or, unless the runner continues on error,
+	// because another task failed.
+	Skipped  bool
+	Duration time.Duration
+}
+
+// NewTaskRunner returns a TaskRunner running up to concurrency tasks at the
+// same time.
+func NewTaskRunner(client client.Interface, reporter StatusReporter, concurrency int, tasks []*TaskSpec) *TaskRunner {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
 	return &TaskRunner{
-		client: client,
-		tasks:  tasks,
+		client:      client,
+		reporter:    reporter,
+		concurrency: concurrency,
+		tasks:       tasks,
 	}
 }
 
-func (tl *TaskRunner) RunAll() error {
+// ContinueOnError makes the TaskRunner keep running the tasks that do not
+// depend on a failed task, instead of stopping at the first failure, and
+// returns it.
+func (tl *TaskRunner) ContinueOnError() *TaskRunner {
+	tl.continueOnError = true
+	return tl
+}
+
+// Results returns the results of the tasks in the last RunAll, in the order
+// of the tasks.
+func (tl *TaskRunner) Results() []TaskResult {
+	tl.mtx.Lock()
+	defer tl.mtx.Unlock()
+
+	res := make([]TaskResult, 0, len(tl.tasks))
+	for _, ts := range tl.tasks {
+		if r, ok := tl.results[ts]; ok {
+			res = append(res, *r)
+		}
+	}
+	return res
+}
+
+func (tl *TaskRunner) setResult(ts *TaskSpec, r *TaskResult) {
+	tl.mtx.Lock()
+	defer tl.mtx.Unlock()
+	tl.results[ts] = r
+}
+
+type taskResult struct {
+	ts  *TaskSpec
+	err error
+}
+
+// RunAll runs the tasks concurrently, a task only being started once all of
+// its dependencies succeeded. Dependencies on tasks that are not run by this
+// TaskRunner are considered satisfied. Once a task fails no new task is
+// started, unless the TaskRunner continues on error, in which case only the
+// tasks depending on the failed task are skipped. No new task is started
+// either once ctx is done, and ctx is passed to the tasks so that they can
+// abort. The errors of all failed tasks are returned as an aggregate.
+func (tl *TaskRunner) RunAll(ctx context.Context) error {
+	tl.mtx.Lock()
+	tl.results = make(map[*TaskSpec]*TaskResult, len(tl.tasks))
+	tl.mtx.Unlock()
+
+	pending := make(map[*TaskSpec]int, len(tl.tasks))
+	dependents := make(map[*TaskSpec][]*TaskSpec, len(tl.tasks))
+	for _, ts := range tl.tasks {
+		pending[ts] = 0
+	}
+	for _, ts := range tl.tasks {
+		for _, dep := range ts.Dependencies {
+			if _, ok := pending[dep]; !ok {
+				continue
+			}
+			pending[ts]++
+			dependents[dep] = append(dependents[dep], ts)
+		}
+	}
+	if err := checkCycles(tl.tasks, pending, dependents); err != nil {
+		return err
+	}
+
+	ready := []*TaskSpec{}
+	for _, ts := range tl.tasks {
+		if pending[ts] == 0 {
+			ready = append(ready, ts)
+		}
+	}
+
+	results := make(chan taskResult)
+	running := 0
+	failed := false
+	errs := []error{}
+	for {
+		for !failed && ctx.Err() == nil && running < tl.concurrency && len(ready) > 0 {
+			ts := ready[0]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- taskResult{ts: ts, err: tl.run(ctx, ts)}
+			}()
+		}
+		if running == 0 {
+			break
+		}
+
+		r := <-results
+		running--
+		if r.err != nil {
+			errs = append(errs, errors.Wrapf(r.err, "running task %v failed", r.ts.Name))
+			if !tl.continueOnError {
+				failed = true
+				continue
+			}
+			tl.skipDependents(r.ts, dependents, errors.Errorf("task %v failed", r.ts.Name))
+			continue
+		}
+		for _, d := range dependents[r.ts] {
+			pending[d]--
+			if pending[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	// The tasks that were not started are skipped because ctx is done or,
+	// without continuing on error, because of the first failure.
+	reason := ctx.Err()
+	if reason == nil {
+		reason = errors.New("a previous task failed")
+	}
+	skipped := false
 	for _, ts := range tl.tasks {
-		glog.V(4).Infof("running task %v", ts.Name)
-		err := tl.ExecuteTask(ts)
-		if err != nil {
-			return errors.Wrapf(err, "running task %v failed", ts.Name)
+		tl.mtx.Lock()
+		_, done := tl.results[ts]
+		tl.mtx.Unlock()
+		if !done {
+			tl.skip(ts, reason)
+			skipped = true
+		}
+	}
+	if skipped && len(errs) == 0 {
+		errs = append(errs, reason)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// skipDependents skips all the tasks depending, directly or not, on ts.
+func (tl *TaskRunner) skipDependents(ts *TaskSpec, dependents map[*TaskSpec][]*TaskSpec, reason error) {
+	for _, d := range dependents[ts] {
+		tl.mtx.Lock()
+		_, done := tl.results[d]
+		tl.mtx.Unlock()
+		if done {
+			continue
+		}
+		tl.skip(d, reason)
+		tl.skipDependents(d, dependents, reason)
+	}
+}
+
+func (tl *TaskRunner) skip(ts *TaskSpec, reason error) {
+	err := errors.Wrap(reason, "skipped")
+	glog.V(4).Infof("skipping task %v: %v", ts.Name, reason)
+	tl.setResult(ts, &TaskResult{Name: ts.Name, Err: err, Skipped: true})
+	tl.reporter.TaskFinished(ts.Name, err)
+}
+
+// checkCycles returns an error if the dependencies of the tasks contain a
+// cycle, as such tasks would never run.
+func checkCycles(tasks []*TaskSpec, pending map[*TaskSpec]int, dependents map[*TaskSpec][]*TaskSpec) error {
+	p := make(map[*TaskSpec]int, len(pending))
+	queue := []*TaskSpec{}
+	for _, ts := range tasks {
+		p[ts] = pending[ts]
+		if p[ts] == 0 {
+			queue = append(queue, ts)
+		}
+	}
+
+	visited := 0
+	for len(queue) > 0 {
+		ts := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, d := range dependents[ts] {
+			p[d]--
+			if p[d] == 0 {
+				queue = append(queue, d)
+			}
 		}
 	}
 
+	if visited != len(tasks) {
+		return errors.New("task dependencies contain a cycle")
+	}
 	return nil
 }
 
-func (tl *TaskRunner) ExecuteTask(ts *TaskSpec) error {
-	return ts.Task.Run()
+func (tl *TaskRunner) run(ctx context.Context, ts *TaskSpec) error {
+	glog.V(4).Infof("running task %v", ts.Name)
+	tl.reporter.TaskStarted(ts.Name)
+	start := time.Now()
+	err := tl.ExecuteTask(ctx, ts)
+	d := time.Since(start)
+	taskDurationSeconds.WithLabelValues(ts.Name).Observe(d.Seconds())
+	taskRunsTotal.WithLabelValues(ts.Name, resultLabel(err)).Inc()
+	tl.setResult(ts, &TaskResult{Name: ts.Name, Err: err, Duration: d})
+	tl.reporter.TaskFinished(ts.Name, err)
+	return err
+}
+
+// ExecuteTask runs the task, passing the wait policy of the task to the
+// client through ctx, and retries it according to its policy.
+func (tl *TaskRunner) ExecuteTask(ctx context.Context, ts *TaskSpec) error {
+	ctx = client.WithWaitPolicy(ctx, ts.Policy.Wait)
+	backoff := ts.Policy.Backoff
+
+	for attempt := 0; ; attempt++ {
+		err := ts.Task.Run(ctx)
+		if err == nil || attempt >= ts.Policy.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+
+		glog.V(4).Infof("task %v failed, retrying in %v: %v", ts.Name, backoff, err)
+		taskRetriesTotal.WithLabelValues(ts.Name).Inc()
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return err
+		}
+
+		backoff *= 2
+		if ts.Policy.MaxBackoff > 0 && backoff > ts.Policy.MaxBackoff {
+			backoff = ts.Policy.MaxBackoff
+		}
+	}
 }
 
 func NewTaskSpec(name string, task Task) *TaskSpec {
@@ -58,8 +301,37 @@ func NewTaskSpec(name string, task Task) *TaskSpec {
 type TaskSpec struct {
 	Name string
 	Task Task
+	// Dependencies are the tasks that must succeed before this task is
+	// run.
+	Dependencies []*TaskSpec
+	Policy       Policy
+}
+
+// Policy controls how long a task waits for its objects to be ready, and
+// how often it is retried when it fails.
+type Policy struct {
+	Wait client.WaitPolicy
+	// MaxRetries is the number of times a failed task is retried before
+	// its failure is reported.
+	MaxRetries int
+	// Backoff is the delay before the first retry. It doubles on every
+	// retry, up to MaxBackoff if set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// DependsOn adds tasks to the dependencies of the task and returns it.
+func (ts *TaskSpec) DependsOn(deps ...*TaskSpec) *TaskSpec {
+	ts.Dependencies = append(ts.Dependencies, deps...)
+	return ts
+}
+
+// WithPolicy sets the policy of the task and returns it.
+func (ts *TaskSpec) WithPolicy(p Policy) *TaskSpec {
+	ts.Policy = p
+	return ts
 }
 
 type Task interface {
-	Run() error
+	Run(ctx context.Context) error
 }
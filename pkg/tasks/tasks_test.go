@@ -0,0 +1,283 @@
+// Copyright 2018 The Cluster Monitoring Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openshift/cluster-monitoring-operator/pkg/client/fake"
+)
+
+// actions returns the actions performed by the fake client as strings.
+func actions(c *fake.Client) []string {
+	res := []string{}
+	for _, a := range c.Actions() {
+		res = append(res, a.String())
+	}
+	return res
+}
+
+// contains returns true if actions contains action.
+func contains(actions []string, action string) bool {
+	for _, a := range actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+type nopReporter struct{}
+
+func (nopReporter) TaskStarted(name string)             {}
+func (nopReporter) TaskFinished(name string, err error) {}
+
+type funcTask func() error
+
+func (f funcTask) Run(ctx context.Context) error { return f() }
+
+// recorder records the order in which tasks finish.
+type recorder struct {
+	mtx      sync.Mutex
+	finished []string
+	donec    map[string]chan struct{}
+}
+
+// task returns a task recording that it finished and returning err.
+func (r *recorder) task(name string, err error) *TaskSpec {
+	return r.waitingTask(name, nil, err)
+}
+
+// waitingTask returns a task that only finishes once wait is closed. It
+// fails if wait is not closed in time, which means the tasks it waits for
+// were not run concurrently.
+func (r *recorder) waitingTask(name string, wait <-chan struct{}, err error) *TaskSpec {
+	return NewTaskSpec(name, funcTask(func() error {
+		if wait != nil {
+			select {
+			case <-wait:
+			case <-time.After(10 * time.Second):
+				return errors.New("timed out waiting for another task")
+			}
+		}
+		r.mtx.Lock()
+		r.finished = append(r.finished, name)
+		close(r.doneLocked(name))
+		r.mtx.Unlock()
+		return err
+	}))
+}
+
+// done returns a channel closed once the task with the given name finished.
+func (r *recorder) done(name string) <-chan struct{} {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	return r.doneLocked(name)
+}
+
+func (r *recorder) doneLocked(name string) chan struct{} {
+	if r.donec == nil {
+		r.donec = map[string]chan struct{}{}
+	}
+	if _, ok := r.donec[name]; !ok {
+		r.donec[name] = make(chan struct{})
+	}
+	return r.donec[name]
+}
+
+func (r *recorder) index(name string) int {
+	for i, n := range r.finished {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRunAllHonorsDependencies(t *testing.T) {
+	r := &recorder{}
+	// The operator task only finishes once node-exporter did, which
+	// would time out if node-exporter waited for it.
+	operator := r.waitingTask("operator", r.done("node-exporter"), nil)
+	grafana := r.task("grafana", nil)
+	prometheus := r.task("prometheus", nil).DependsOn(operator, grafana)
+	nodeExporter := r.task("node-exporter", nil)
+
+	tl := NewTaskRunner(nil, nopReporter{}, 2, []*TaskSpec{operator, grafana, prometheus, nodeExporter})
+	if err := tl.RunAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(r.finished) != 4 {
+		t.Fatalf("expected 4 tasks to run, got %v", r.finished)
+	}
+	if r.index("prometheus") < r.index("operator") || r.index("prometheus") < r.index("grafana") {
+		t.Fatalf("prometheus ran before its dependencies: %v", r.finished)
+	}
+	// node-exporter does not wait for the slow operator task.
+	if r.index("node-exporter") > r.index("operator") {
+		t.Fatalf("node-exporter waited for an unrelated task: %v", r.finished)
+	}
+}
+
+func TestRunAllStopsOnFailure(t *testing.T) {
+	r := &recorder{}
+	failing := r.task("failing", errors.New("boom"))
+	// The slow task is still running when the failing one fails.
+	slow := r.waitingTask("slow", r.done("failing"), errors.New("bang"))
+	dependent := r.task("dependent", nil).DependsOn(failing)
+
+	tl := NewTaskRunner(nil, nopReporter{}, 2, []*TaskSpec{failing, slow, dependent})
+	err := tl.RunAll(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if r.index("dependent") != -1 {
+		t.Fatalf("dependent task ran although its dependency failed: %v", r.finished)
+	}
+	// The errors of all running tasks are collected.
+	for _, msg := range []string{"running task failing failed: boom", "running task slow failed: bang"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error to contain %q, got %q", msg, err)
+		}
+	}
+}
+
+func TestRunAllIgnoresDependenciesNotRun(t *testing.T) {
+	r := &recorder{}
+	other := r.task("other", nil)
+	task := r.task("task", nil).DependsOn(other)
+
+	tl := NewTaskRunner(nil, nopReporter{}, 1, []*TaskSpec{task})
+	if err := tl.RunAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.finished) != 1 || r.finished[0] != "task" {
+		t.Fatalf("expected only task to run, got %v", r.finished)
+	}
+}
+
+func TestRunAllDetectsCycles(t *testing.T) {
+	r := &recorder{}
+	a := r.task("a", nil)
+	b := r.task("b", nil).DependsOn(a)
+	a.DependsOn(b)
+
+	tl := NewTaskRunner(nil, nopReporter{}, 1, []*TaskSpec{a, b})
+	if err := tl.RunAll(context.Background()); err == nil {
+		t.Fatal("expected an error for cyclic dependencies")
+	}
+}
+
+func TestRunAllContinueOnError(t *testing.T) {
+	r := &recorder{}
+	grafana := r.task("grafana", errors.New("route not admitted"))
+	prometheus := r.task("prometheus", nil).DependsOn(grafana)
+	rules := r.task("rules", nil).DependsOn(prometheus)
+	nodeExporter := r.task("node-exporter", nil)
+	kubeStateMetrics := r.task("kube-state-metrics", errors.New("rollout failed"))
+
+	tl := NewTaskRunner(nil, nopReporter{}, 1, []*TaskSpec{grafana, prometheus, rules, nodeExporter, kubeStateMetrics}).ContinueOnError()
+	err := tl.RunAll(context.Background())
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, msg := range []string{"running task grafana failed", "running task kube-state-metrics failed"} {
+		if !strings.Contains(err.Error(), msg) {
+			t.Errorf("expected error to contain %q, got %q", msg, err)
+		}
+	}
+	if r.index("node-exporter") == -1 {
+		t.Fatalf("independent task was not run: %v", r.finished)
+	}
+
+	results := tl.Results()
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %v", results)
+	}
+	for _, res := range results {
+		switch res.Name {
+		case "prometheus", "rules":
+			if !res.Skipped || res.Err == nil {
+				t.Errorf("expected %s to be skipped, got %+v", res.Name, res)
+			}
+		case "node-exporter":
+			if res.Skipped || res.Err != nil {
+				t.Errorf("expected %s to succeed, got %+v", res.Name, res)
+			}
+		default:
+			if res.Skipped || res.Err == nil {
+				t.Errorf("expected %s to fail, got %+v", res.Name, res)
+			}
+		}
+	}
+}
+
+func TestRunAllCanceled(t *testing.T) {
+	r := &recorder{}
+	ctx, cancel := context.WithCancel(context.Background())
+	first := NewTaskSpec("first", funcTask(func() error {
+		cancel()
+		return nil
+	}))
+	second := r.task("second", nil).DependsOn(first)
+
+	tl := NewTaskRunner(nil, nopReporter{}, 1, []*TaskSpec{first, second}).ContinueOnError()
+	err := tl.RunAll(ctx)
+	if err == nil || !strings.Contains(err.Error(), context.Canceled.Error()) {
+		t.Fatalf("expected the cancellation to be reported, got %v", err)
+	}
+	if r.index("second") != -1 {
+		t.Fatalf("task was started after the context was canceled: %v", r.finished)
+	}
+	for _, res := range tl.Results() {
+		if res.Name == "second" && !res.Skipped {
+			t.Errorf("expected second to be skipped, got %+v", res)
+		}
+	}
+}
+
+func TestRunAllRetries(t *testing.T) {
+	attempts := 0
+	flaky := NewTaskSpec("flaky", funcTask(func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})).WithPolicy(Policy{MaxRetries: 2, Backoff: time.Millisecond})
+
+	tl := NewTaskRunner(nil, nopReporter{}, 1, []*TaskSpec{flaky})
+	if err := tl.RunAll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+
+	attempts = 0
+	flaky.Policy.MaxRetries = 1
+	if err := tl.RunAll(context.Background()); err == nil {
+		t.Fatal("expected an error once the retries are exhausted")
+	}
+	if attempts != 2 {
+		t.Fatalf("expected 2 attempts, got %d", attempts)
+	}
+}